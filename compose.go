@@ -0,0 +1,732 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultComposeStartupTimeout bounds the wait strategy of every compose service
+const DefaultComposeStartupTimeout = time.Minute
+
+// Conditions of depends_on
+const (
+	ServiceStarted               = "service_started"
+	ServiceHealthy               = "service_healthy"
+	ServiceCompletedSuccessfully = "service_completed_successfully"
+)
+
+// ComposeFile is the subset of the compose specification started by Compose.
+// Top level networks, volumes and unsupported service keys are ignored.
+type ComposeFile struct {
+	Name     string                    `yaml:"name"`
+	Services map[string]ComposeService `yaml:"services"`
+	// Dir resolves relative bind mounts, the directory of the file
+	Dir string `yaml:"-"`
+}
+
+// ComposeService is a service of a compose file
+type ComposeService struct {
+	Image       string              `yaml:"image"`
+	Build       interface{}         `yaml:"build"`
+	Command     ShellCommand        `yaml:"command"`
+	Entrypoint  ShellCommand        `yaml:"entrypoint"`
+	Environment ComposeMapping      `yaml:"environment"`
+	Labels      ComposeMapping      `yaml:"labels"`
+	Ports       []string            `yaml:"ports"`
+	DependsOn   ComposeDependsOn    `yaml:"depends_on"`
+	Healthcheck *ComposeHealthcheck `yaml:"healthcheck"`
+	Profiles    []string            `yaml:"profiles"`
+	Volumes     []string            `yaml:"volumes"`
+	Tmpfs       ShellCommand        `yaml:"tmpfs"`
+	Hostname    string              `yaml:"hostname"`
+	User        string              `yaml:"user"`
+	WorkingDir  string              `yaml:"working_dir"`
+	Privileged  bool                `yaml:"privileged"`
+}
+
+// ComposeHealthcheck is the healthcheck of a compose service
+type ComposeHealthcheck struct {
+	Test        HealthcheckTest `yaml:"test"`
+	Interval    time.Duration   `yaml:"interval"`
+	Timeout     time.Duration   `yaml:"timeout"`
+	StartPeriod time.Duration   `yaml:"start_period"`
+	Retries     int             `yaml:"retries"`
+	Disable     bool            `yaml:"disable"`
+}
+
+// ShellCommand is a command given as a list or as a string split like a shell does
+type ShellCommand []string
+
+func (c *ShellCommand) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		words, err := splitShellWords(node.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %v", node.Line, err)
+		}
+		*c = words
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
+}
+
+// HealthcheckTest is the test of a healthcheck, a string runs with the container shell
+type HealthcheckTest []string
+
+func (t *HealthcheckTest) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = HealthcheckTest{"CMD-SHELL", node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*t = list
+	return nil
+}
+
+// ComposeMapping is given as a map or as a list of KEY=VALUE
+type ComposeMapping map[string]string
+
+func (m *ComposeMapping) UnmarshalYAML(node *yaml.Node) error {
+	mapping := make(ComposeMapping)
+	if node.Kind == yaml.SequenceNode {
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		for _, item := range list {
+			key, value, _ := strings.Cut(item, "=")
+			mapping[key] = value
+		}
+	} else {
+		var values map[string]*string
+		if err := node.Decode(&values); err != nil {
+			return err
+		}
+		for key, value := range values {
+			if value != nil {
+				mapping[key] = *value
+			} else {
+				mapping[key] = ""
+			}
+		}
+	}
+	*m = mapping
+	return nil
+}
+
+// ComposeDependsOn maps the services depended on to their condition,
+// given as a list (service_started) or as a map
+type ComposeDependsOn map[string]string
+
+func (d *ComposeDependsOn) UnmarshalYAML(node *yaml.Node) error {
+	dependsOn := make(ComposeDependsOn)
+	if node.Kind == yaml.SequenceNode {
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		for _, service := range list {
+			dependsOn[service] = ServiceStarted
+		}
+	} else {
+		var conditions map[string]struct {
+			Condition string `yaml:"condition"`
+		}
+		if err := node.Decode(&conditions); err != nil {
+			return err
+		}
+		for service, c := range conditions {
+			if c.Condition == "" {
+				c.Condition = ServiceStarted
+			}
+			dependsOn[service] = c.Condition
+		}
+	}
+	*d = dependsOn
+	return nil
+}
+
+// LoadComposeFile reads a compose file, ${VAR}, ${VAR:-default} and ${VAR:?error}
+// are replaced with environment variables
+func LoadComposeFile(path string) (*ComposeFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file: %v", err)
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file: %v", err)
+	}
+	file, err := parseComposeFile(data, os.LookupEnv)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse compose file %s: %v", path, err)
+	}
+	file.Dir = dir
+	if file.Name == "" {
+		file.Name = filepath.Base(dir)
+	}
+	return file, nil
+}
+
+func parseComposeFile(data []byte, lookup func(string) (string, bool)) (*ComposeFile, error) {
+	var errs []error
+	expanded := os.Expand(string(data), func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		value, err := interpolate(name, lookup)
+		errs = append(errs, err)
+		return value
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	var file ComposeFile
+	if err := yaml.Unmarshal([]byte(expanded), &file); err != nil {
+		return nil, err
+	}
+	if len(file.Services) == 0 {
+		return nil, errors.New("no services")
+	}
+	for name, service := range file.Services {
+		if service.Image == "" {
+			if service.Build != nil {
+				return nil, fmt.Errorf("service %s: build is not supported, use an image", name)
+			}
+			return nil, fmt.Errorf("service %s: image is required", name)
+		}
+		for _, port := range service.Ports {
+			if _, err := nat.ParsePortSpec(port); err != nil {
+				return nil, fmt.Errorf("service %s: %v", name, err)
+			}
+		}
+	}
+	return &file, nil
+}
+
+var interpolationName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+
+// interpolate resolves the content of a ${...} expression
+func interpolate(expr string, lookup func(string) (string, bool)) (string, error) {
+	name := interpolationName.FindString(expr)
+	op, arg := expr[len(name):], ""
+	known := op == ""
+	for _, prefix := range []string{":-", ":?", "-", "?"} {
+		if strings.HasPrefix(op, prefix) {
+			op, arg, known = prefix, op[len(prefix):], true
+			break
+		}
+	}
+	if name == "" || !known {
+		return "", fmt.Errorf("invalid interpolation ${%s}", expr)
+	}
+	value, ok := lookup(name)
+	// the colon forms treat empty variables as unset
+	if ok && (value != "" || !strings.HasPrefix(op, ":")) {
+		return value, nil
+	}
+	switch op {
+	case "":
+		return "", nil
+	case ":-", "-":
+		return arg, nil
+	default:
+		return "", fmt.Errorf("variable %s is required: %s", name, arg)
+	}
+}
+
+// splitShellWords splits a command line on spaces outside of quotes
+func splitShellWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inWord = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// ComposeOptions ...
+type ComposeOptions struct {
+	// Project prefixes the network and container names, the name of the file by default.
+	// A unique suffix is always appended so that stacks of parallel tests do not collide.
+	Project string
+	// Profiles enables the services of these profiles, services without profiles always start
+	Profiles []string
+	// WaitFor replaces the wait strategy of a service, which waits for its healthcheck by default
+	WaitFor map[string]wait.Strategy
+	// StartupTimeout bounds the wait of every service, Config.StartupTimeout or
+	// DefaultComposeStartupTimeout when 0
+	StartupTimeout time.Duration
+}
+
+// ComposeStack is a compose file started through the docker API
+type ComposeStack struct {
+	Project string
+	// Network is the network all services are attached to under their service name
+	Network string
+
+	mu         sync.Mutex
+	network    testcontainers.Network
+	containers map[string]testcontainers.Container
+	volumes    []string
+}
+
+// Compose starts the enabled services of a compose file, each one as soon as the
+// services it depends on meet their condition. On failure everything started is removed.
+func Compose(ctx context.Context, path string, options ComposeOptions) (*ComposeStack, error) {
+	file, err := LoadComposeFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ComposeUp(ctx, file, options)
+}
+
+// ComposeUp is Compose for a parsed file
+func ComposeUp(ctx context.Context, file *ComposeFile, options ComposeOptions) (stack *ComposeStack, err error) {
+	services, err := enabledServices(file, options.Profiles)
+	if err != nil {
+		return nil, err
+	}
+	order, err := composeOrder(file, services)
+	if err != nil {
+		return nil, err
+	}
+	if options.StartupTimeout <= 0 {
+		config, err := LoadConfig()
+		if err != nil {
+			return nil, err
+		}
+		options.StartupTimeout = config.StartupTimeout
+	}
+	if options.StartupTimeout <= 0 {
+		options.StartupTimeout = DefaultComposeStartupTimeout
+	}
+
+	project := options.Project
+	if project == "" {
+		project = file.Name
+	}
+	project = composeProjectName(project) + "-" + UniqueID()[:8]
+	stack = &ComposeStack{
+		Project:    project,
+		Network:    project + "_default",
+		containers: make(map[string]testcontainers.Container),
+	}
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, stack.Down(context.Background()))
+			stack = nil
+		}
+	}()
+
+	stack.network, err = CreateNetwork(ctx, testcontainers.NetworkRequest{
+		Driver:         "bridge",
+		Name:           stack.Network,
+		Attachable:     true,
+		CheckDuplicate: true,
+		Labels:         map[string]string{"com.docker.compose.project": project},
+	}, 0)
+	if err != nil {
+		return stack, err
+	}
+
+	return stack, startInOrder(file, order, func(name string) error {
+		return stack.start(ctx, file, name, options)
+	})
+}
+
+// startInOrder starts every service once its dependencies are started, services whose
+// dependencies failed are not started and reported as skipped
+func startInOrder(file *ComposeFile, order []string, start func(name string) error) error {
+	type result struct {
+		done chan struct{}
+		err  error
+	}
+	results := make(map[string]*result, len(order))
+	for _, name := range order {
+		results[name] = &result{done: make(chan struct{})}
+	}
+	for _, name := range order {
+		go func(name string, r *result) {
+			defer close(r.done)
+			deps := make([]string, 0, len(file.Services[name].DependsOn))
+			for dep := range file.Services[name].DependsOn {
+				deps = append(deps, dep)
+			}
+			sort.Strings(deps)
+			for _, dep := range deps {
+				// the err of a dependency is set before its done is closed
+				<-results[dep].done
+				if results[dep].err != nil {
+					r.err = fmt.Errorf("skipped service %s: dependency %s failed", name, dep)
+					return
+				}
+			}
+			if err := start(name); err != nil {
+				r.err = fmt.Errorf("failed to start service %s: %w", name, err)
+			}
+		}(name, results[name])
+	}
+	var failures []error
+	for _, name := range order {
+		<-results[name].done
+		failures = append(failures, results[name].err)
+	}
+	return errors.Join(failures...)
+}
+
+func (s *ComposeStack) start(ctx context.Context, file *ComposeFile, name string, options ComposeOptions) error {
+	req, err := s.request(file, name)
+	if err != nil {
+		return err
+	}
+	req.WaitingFor = composeWaitStrategy(file, name, options)
+
+	startup := NewStartup(name)
+	c, err := startup.StartContainer(ctx, req)
+	if err != nil {
+		return err
+	}
+	startup.Done()
+	s.mu.Lock()
+	s.containers[name] = c
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *ComposeStack) request(file *ComposeFile, name string) (testcontainers.ContainerRequest, error) {
+	service := file.Services[name]
+	req := testcontainers.ContainerRequest{
+		Image:          service.Image,
+		Entrypoint:     service.Entrypoint,
+		Cmd:            service.Command,
+		Env:            service.Environment,
+		ExposedPorts:   service.Ports,
+		Name:           s.Project + "-" + name,
+		Hostname:       service.Hostname,
+		User:           service.User,
+		Privileged:     service.Privileged,
+		Networks:       []string{s.Network},
+		NetworkAliases: map[string][]string{s.Network: {name}},
+		Labels: withLabels(map[string]string{
+			"com.docker.compose.project": s.Project,
+			"com.docker.compose.service": name,
+		}, service.Labels),
+	}
+	WithSessionLabels(&req, "")
+	for _, tmpfs := range service.Tmpfs {
+		if req.Tmpfs == nil {
+			req.Tmpfs = make(map[string]string)
+		}
+		target, mountOptions, _ := strings.Cut(tmpfs, ":")
+		req.Tmpfs[target] = mountOptions
+	}
+
+	var anonymous []string
+	for _, volume := range service.Volumes {
+		parts := strings.Split(volume, ":")
+		if len(parts) == 1 {
+			anonymous = append(anonymous, parts[0])
+			continue
+		}
+		source, target := parts[0], testcontainers.ContainerMountTarget(parts[1])
+		var mount testcontainers.ContainerMount
+		switch {
+		case strings.HasPrefix(source, "."), filepath.IsAbs(source):
+			if !filepath.IsAbs(source) {
+				source = filepath.Join(file.Dir, source)
+			}
+			mount = testcontainers.BindMount(source, target)
+		case strings.HasPrefix(source, "~"):
+			return req, fmt.Errorf("volume %s: home relative paths are not supported", volume)
+		default:
+			source = s.Project + "_" + source
+			s.mu.Lock()
+			s.volumes = append(s.volumes, source)
+			s.mu.Unlock()
+			trackVolume(source)
+			mount = testcontainers.VolumeMount(source, target)
+		}
+		mount.ReadOnly = len(parts) > 2 && strings.Contains(parts[2], "ro")
+		req.Mounts = append(req.Mounts, mount)
+	}
+
+	health := service.Healthcheck
+	req.ConfigModifier = func(config *container.Config) {
+		config.WorkingDir = service.WorkingDir
+		for _, target := range anonymous {
+			if config.Volumes == nil {
+				config.Volumes = make(map[string]struct{})
+			}
+			config.Volumes[target] = struct{}{}
+		}
+		switch {
+		case health == nil:
+		case health.Disable:
+			config.Healthcheck = &container.HealthConfig{Test: []string{"NONE"}}
+		default:
+			config.Healthcheck = &container.HealthConfig{
+				Test:        health.Test,
+				Interval:    health.Interval,
+				Timeout:     health.Timeout,
+				StartPeriod: health.StartPeriod,
+				Retries:     health.Retries,
+			}
+		}
+	}
+	return req, nil
+}
+
+// composeWaitStrategy returns the strategy meeting the conditions other services depend on
+func composeWaitStrategy(file *ComposeFile, name string, options ComposeOptions) wait.Strategy {
+	if strategy, ok := options.WaitFor[name]; ok {
+		return strategy
+	}
+	for _, service := range file.Services {
+		if service.DependsOn[name] == ServiceCompletedSuccessfully {
+			return &completedStrategy{timeout: options.StartupTimeout}
+		}
+	}
+	if health := file.Services[name].Healthcheck; health != nil && !health.Disable && len(health.Test) > 0 {
+		return wait.ForHealthCheck().WithStartupTimeout(options.StartupTimeout)
+	}
+	return nil
+}
+
+// completedStrategy waits for a one-shot service to exit with 0
+type completedStrategy struct {
+	timeout time.Duration
+}
+
+func (s *completedStrategy) WaitUntilReady(ctx context.Context, target wait.StrategyTarget) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	for {
+		state, err := target.State(ctx)
+		if err != nil {
+			return err
+		}
+		if !state.Running && state.Status != "created" {
+			if state.ExitCode != 0 {
+				return fmt.Errorf("exited with code %d", state.ExitCode)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("service did not complete: %w", ctx.Err())
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// enabledServices returns the services without profiles and those of an enabled profile
+func enabledServices(file *ComposeFile, profiles []string) (map[string]bool, error) {
+	enabled := make(map[string]bool)
+	for name, service := range file.Services {
+		if len(service.Profiles) == 0 {
+			enabled[name] = true
+		}
+		for _, profile := range service.Profiles {
+			for _, p := range profiles {
+				if p == profile {
+					enabled[name] = true
+				}
+			}
+		}
+	}
+	for name := range enabled {
+		for dep, condition := range file.Services[name].DependsOn {
+			depService, ok := file.Services[dep]
+			switch {
+			case !ok:
+				return nil, fmt.Errorf("service %s depends on undefined service %s", name, dep)
+			case !enabled[dep]:
+				return nil, fmt.Errorf("service %s depends on service %s of a disabled profile", name, dep)
+			case condition == ServiceHealthy && depService.Healthcheck == nil:
+				return nil, fmt.Errorf("service %s depends on service %s being healthy, which has no healthcheck", name, dep)
+			case condition != ServiceStarted && condition != ServiceHealthy && condition != ServiceCompletedSuccessfully:
+				return nil, fmt.Errorf("service %s: unknown depends_on condition %s", name, condition)
+			}
+		}
+	}
+	return enabled, nil
+}
+
+// composeOrder sorts the enabled services so that dependencies come first and rejects cycles
+func composeOrder(file *ComposeFile, enabled map[string]bool) ([]string, error) {
+	pending := make(map[string]int, len(enabled))
+	dependents := make(map[string][]string)
+	for name := range enabled {
+		pending[name] = len(file.Services[name].DependsOn)
+		for dep := range file.Services[name].DependsOn {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+	var ready, order []string
+	for name, n := range pending {
+		if n == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dependent := range dependents[name] {
+			if pending[dependent]--; pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(order) != len(enabled) {
+		var cycle []string
+		for name, n := range pending {
+			if n > 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		sort.Strings(cycle)
+		return nil, fmt.Errorf("dependency cycle between services %s", strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+var projectNameInvalid = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+func composeProjectName(name string) string {
+	name = strings.Trim(projectNameInvalid.ReplaceAllString(strings.ToLower(name), "-"), "-_")
+	if name == "" {
+		return "compose"
+	}
+	return name
+}
+
+// Services returns the names of the started services
+func (s *ComposeStack) Services() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.containers))
+	for name := range s.containers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Container returns the container of a service, nil if it was not started
+func (s *ComposeStack) Container(service string) testcontainers.Container {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.containers[service]
+}
+
+// Endpoint returns the host:port a container port of a service is published on
+func (s *ComposeStack) Endpoint(ctx context.Context, service string, port nat.Port) (string, error) {
+	c := s.Container(service)
+	if c == nil {
+		return "", fmt.Errorf("service %s is not started", service)
+	}
+	host, err := ContainerHost(ctx, c)
+	if err != nil {
+		return "", fmt.Errorf("failed to get host of service %s: %v", service, err)
+	}
+	mapped, err := c.MappedPort(ctx, port)
+	if err != nil {
+		return "", fmt.Errorf("failed to get port %s of service %s: %v", port, service, err)
+	}
+	return fmt.Sprintf("%s:%s", host, mapped.Port()), nil
+}
+
+// Down removes the containers, the network and the named volumes of the stack
+func (s *ComposeStack) Down(ctx context.Context) error {
+	s.mu.Lock()
+	containers := s.containers
+	s.containers = make(map[string]testcontainers.Container)
+	volumes := s.volumes
+	s.volumes = nil
+	network := s.network
+	s.network = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for name, c := range containers {
+		if err := c.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove service %s: %w", name, err))
+		}
+	}
+	if network != nil {
+		if err := network.Remove(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove network %s: %w", s.Network, err))
+		}
+	}
+	if len(volumes) > 0 {
+		client, err := NewDockerClient()
+		if err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		defer client.Close()
+		for _, volume := range volumes {
+			if err := client.VolumeRemove(ctx, volume, true); err != nil && !dockerclient.IsErrNotFound(err) {
+				errs = append(errs, fmt.Errorf("failed to remove volume %s: %w", volume, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
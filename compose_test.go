@@ -0,0 +1,243 @@
+package testcontainers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const testComposeFile = `
+name: Shop Backend
+services:
+  mongo:
+    image: mongo:${MONGO_TAG:-6.0.2}
+    command: mongod --bind_ip_all --setParameter "diagnosticDataCollectionEnabled=false"
+    volumes:
+      - ./seed:/docker-entrypoint-initdb.d:ro
+      - mongo-data:/data/db
+    healthcheck:
+      test: ["CMD", "mongosh", "--eval", "db.runCommand('ping')"]
+      interval: 2s
+      retries: 10
+  redis:
+    image: redis:7.0.5
+    ports:
+      - 6379
+    environment:
+      - REDIS_ARGS=--save ""
+  migrate:
+    image: alpine:3.18
+    command: ["sh", "-c", "echo $$HOME"]
+    depends_on:
+      mongo:
+        condition: service_healthy
+  api:
+    image: shop/api:${API_TAG:?set the api tag}
+    ports:
+      - "127.0.0.1:8080:8080/tcp"
+    environment:
+      MONGO: mongodb://mongo:27017
+      EMPTY:
+    depends_on:
+      migrate:
+        condition: service_completed_successfully
+      redis:
+        condition: service_started
+  debug:
+    image: busybox
+    profiles: [debug]
+    depends_on: [api]
+`
+
+func TestParseComposeFile(t *testing.T) {
+	env := map[string]string{"API_TAG": "1.2.3"}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	file, err := parseComposeFile([]byte(testComposeFile), lookup)
+	require.NoError(t, err)
+	require.Equal(t, "Shop Backend", file.Name)
+	require.Len(t, file.Services, 5)
+
+	mongo := file.Services["mongo"]
+	require.Equal(t, "mongo:6.0.2", mongo.Image)
+	require.Equal(t, ShellCommand{"mongod", "--bind_ip_all", "--setParameter", "diagnosticDataCollectionEnabled=false"}, mongo.Command)
+	require.Equal(t, HealthcheckTest{"CMD", "mongosh", "--eval", "db.runCommand('ping')"}, mongo.Healthcheck.Test)
+	require.Equal(t, 2*time.Second, mongo.Healthcheck.Interval)
+	require.Equal(t, 10, mongo.Healthcheck.Retries)
+
+	require.Equal(t, []string{"6379"}, file.Services["redis"].Ports)
+	require.Equal(t, ComposeMapping{"REDIS_ARGS": `--save ""`}, file.Services["redis"].Environment)
+	require.Equal(t, ShellCommand{"sh", "-c", "echo $HOME"}, file.Services["migrate"].Command)
+
+	api := file.Services["api"]
+	require.Equal(t, "shop/api:1.2.3", api.Image)
+	require.Equal(t, ComposeMapping{"MONGO": "mongodb://mongo:27017", "EMPTY": ""}, api.Environment)
+	require.Equal(t, ComposeDependsOn{"migrate": ServiceCompletedSuccessfully, "redis": ServiceStarted}, api.DependsOn)
+	require.Equal(t, ComposeDependsOn{"api": ServiceStarted}, file.Services["debug"].DependsOn)
+
+	delete(env, "API_TAG")
+	_, err = parseComposeFile([]byte(testComposeFile), lookup)
+	require.ErrorContains(t, err, "variable API_TAG is required: set the api tag")
+
+	_, err = parseComposeFile([]byte("services:\n  app:\n    build: .\n"), lookup)
+	require.ErrorContains(t, err, "build is not supported")
+	_, err = parseComposeFile([]byte("services:\n  app:\n    image: app\n    ports: [\"80:http\"]\n"), lookup)
+	require.Error(t, err)
+}
+
+func TestInterpolate(t *testing.T) {
+	lookup := func(name string) (string, bool) {
+		value, ok := map[string]string{"SET": "value", "EMPTY": ""}[name]
+		return value, ok
+	}
+	for expr, want := range map[string]string{
+		"SET":              "value",
+		"UNSET":            "",
+		"UNSET:-default":   "default",
+		"EMPTY:-default":   "default",
+		"EMPTY-default":    "",
+		"UNSET-a-b":        "a-b",
+		"SET:?is required": "value",
+	} {
+		value, err := interpolate(expr, lookup)
+		require.NoError(t, err, expr)
+		require.Equal(t, want, value, expr)
+	}
+	for _, expr := range []string{"EMPTY:?missing", "UNSET?missing", "SET!x", "1SET"} {
+		_, err := interpolate(expr, lookup)
+		require.Error(t, err, expr)
+	}
+}
+
+func TestSplitShellWords(t *testing.T) {
+	words, err := splitShellWords(`sh -c 'echo "a b"' c\ d ""`)
+	require.NoError(t, err)
+	require.Equal(t, []string{"sh", "-c", `echo "a b"`, "c d", ""}, words)
+
+	_, err = splitShellWords(`echo "a`)
+	require.Error(t, err)
+}
+
+func TestComposeOrder(t *testing.T) {
+	file, err := parseComposeFile([]byte(testComposeFile), func(string) (string, bool) { return "1", true })
+	require.NoError(t, err)
+
+	enabled, err := enabledServices(file, nil)
+	require.NoError(t, err)
+	order, err := composeOrder(file, enabled)
+	require.NoError(t, err)
+	require.Equal(t, []string{"mongo", "migrate", "redis", "api"}, order)
+
+	enabled, err = enabledServices(file, []string{"debug"})
+	require.NoError(t, err)
+	order, err = composeOrder(file, enabled)
+	require.NoError(t, err)
+	require.Equal(t, "debug", order[len(order)-1])
+
+	redis := file.Services["redis"]
+	redis.Profiles = []string{"cache"}
+	file.Services["redis"] = redis
+	_, err = enabledServices(file, nil)
+	require.ErrorContains(t, err, "service api depends on service redis of a disabled profile")
+
+	cyclic, err := parseComposeFile([]byte(`
+services:
+  a: {image: a, depends_on: [b]}
+  b: {image: b, depends_on: [c]}
+  c: {image: c, depends_on: [a]}
+  d: {image: d}
+`), func(string) (string, bool) { return "", false })
+	require.NoError(t, err)
+	enabled, err = enabledServices(cyclic, nil)
+	require.NoError(t, err)
+	_, err = composeOrder(cyclic, enabled)
+	require.ErrorContains(t, err, "dependency cycle between services a, b, c")
+
+	unhealthy, err := parseComposeFile([]byte(`
+services:
+  db: {image: db}
+  app: {image: app, depends_on: {db: {condition: service_healthy}}}
+`), func(string) (string, bool) { return "", false })
+	require.NoError(t, err)
+	_, err = enabledServices(unhealthy, nil)
+	require.ErrorContains(t, err, "which has no healthcheck")
+}
+
+func TestStartInOrder(t *testing.T) {
+	file, err := parseComposeFile([]byte(`
+services:
+  db: {image: db}
+  migrate: {image: migrate, depends_on: [db]}
+  api: {image: api, depends_on: [migrate]}
+  cache: {image: cache}
+`), func(string) (string, bool) { return "", false })
+	require.NoError(t, err)
+	enabled, err := enabledServices(file, nil)
+	require.NoError(t, err)
+	order, err := composeOrder(file, enabled)
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	var started []string
+	err = startInOrder(file, order, func(name string) error {
+		if name == "db" {
+			return errors.New("boom")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		started = append(started, name)
+		return nil
+	})
+	require.ErrorContains(t, err, "failed to start service db: boom")
+	require.ErrorContains(t, err, "skipped service migrate: dependency db failed")
+	require.ErrorContains(t, err, "skipped service api: dependency migrate failed")
+	require.Equal(t, []string{"cache"}, started)
+}
+
+func TestComposeRequest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docker-compose.yml")
+	require.NoError(t, os.WriteFile(path, []byte(testComposeFile), 0o644))
+	t.Setenv("API_TAG", "1")
+	file, err := LoadComposeFile(path)
+	require.NoError(t, err)
+
+	stack := &ComposeStack{Project: "shop-backend-1234", Network: "shop-backend-1234_default"}
+	req, err := stack.request(file, "mongo")
+	require.NoError(t, err)
+	require.Equal(t, "shop-backend-1234-mongo", req.Name)
+	require.Equal(t, []string{"shop-backend-1234_default"}, req.Networks)
+	require.Equal(t, map[string][]string{"shop-backend-1234_default": {"mongo"}}, req.NetworkAliases)
+	require.Equal(t, "mongo", req.Labels["com.docker.compose.service"])
+	require.Equal(t, SessionID(), req.Labels[LabelSessionID])
+	require.Len(t, req.Mounts, 2)
+	require.Equal(t, testcontainers.GenericBindMountSource{HostPath: filepath.Join(dir, "seed")}, req.Mounts[0].Source)
+	require.True(t, req.Mounts[0].ReadOnly)
+	require.Equal(t, testcontainers.GenericVolumeMountSource{Name: "shop-backend-1234_mongo-data"}, req.Mounts[1].Source)
+	require.Equal(t, []string{"shop-backend-1234_mongo-data"}, stack.volumes)
+
+	var config container.Config
+	req.ConfigModifier(&config)
+	require.Equal(t, []string{"CMD", "mongosh", "--eval", "db.runCommand('ping')"}, config.Healthcheck.Test)
+	require.Equal(t, 10, config.Healthcheck.Retries)
+
+	options := ComposeOptions{StartupTimeout: time.Second, WaitFor: map[string]wait.Strategy{"redis": wait.ForLog("Ready")}}
+	require.IsType(t, &wait.HealthStrategy{}, composeWaitStrategy(file, "mongo", options))
+	require.IsType(t, &completedStrategy{}, composeWaitStrategy(file, "migrate", options))
+	require.IsType(t, &wait.LogStrategy{}, composeWaitStrategy(file, "redis", options))
+	require.Nil(t, composeWaitStrategy(file, "api", options))
+
+	require.Equal(t, "shop-backend", composeProjectName("Shop Backend!"))
+	require.Equal(t, "compose", composeProjectName("???"))
+}
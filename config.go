@@ -0,0 +1,294 @@
+package testcontainers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ConfigFileName is looked up in the root of the go module under test, then in the home directory
+	ConfigFileName = ".testcontainers.yaml"
+	// EnvConfigFile points to a configuration file used instead of the lookup
+	EnvConfigFile = "TESTCONTAINERS_CONFIG"
+)
+
+const (
+	// DefaultNetworkTimeout is the time CreateNetwork retries for when not configured
+	DefaultNetworkTimeout = 2 * time.Minute
+)
+
+// Config holds the defaults applied by every module before its per-call options.
+// It is read from ConfigFileName and overridden by TESTCONTAINERS_* environment variables,
+// e.g. TESTCONTAINERS_STARTUP_TIMEOUT=2m or TESTCONTAINERS_KAFKA_LOG_LEVEL=INFO.
+type Config struct {
+	// StartupTimeout is the default startup timeout of the modules, it applies when the per-call
+	// options leave it unset, 0 keeps the module default
+	StartupTimeout time.Duration `yaml:"startup_timeout"`
+	NetworkTimeout time.Duration `yaml:"network_timeout"`
+	// LogLevel is the server log level of modules that have one, e.g. kafka and zookeeper
+	LogLevel string `yaml:"log_level"`
+	// Reuse keeps named containers between runs and starts them only once
+	Reuse bool `yaml:"reuse"`
+	// KeepFailed leaves containers that failed to start behind for debugging
+	KeepFailed      bool   `yaml:"keep_failed"`
+	InMemoryStorage bool   `yaml:"in_memory_storage"`
+	Offline         bool   `yaml:"offline"`
+	ImageArchives   string `yaml:"image_archives"`
+	RegistryMirror  string `yaml:"registry_mirror"`
+	// Backoff is used to retry docker operations such as CreateNetwork
+	Backoff BackoffConfig `yaml:"backoff"`
+	// Modules override the settings above per module, e.g. modules.mongo.image
+	Modules map[string]ModuleConfig `yaml:"modules"`
+}
+
+// BackoffConfig configures an exponential backoff
+type BackoffConfig struct {
+	InitialInterval time.Duration `yaml:"initial_interval"`
+	MaxInterval     time.Duration `yaml:"max_interval"`
+	Multiplier      float64       `yaml:"multiplier"`
+}
+
+// ModuleConfig holds the configuration of one module
+type ModuleConfig struct {
+	// Image replaces the catalog image, see Image
+	Image           string        `yaml:"image"`
+	StartupTimeout  time.Duration `yaml:"startup_timeout"`
+	LogLevel        string        `yaml:"log_level"`
+	InMemoryStorage bool          `yaml:"in_memory_storage"`
+}
+
+func defaultConfig() Config {
+	return Config{
+		NetworkTimeout: DefaultNetworkTimeout,
+		Backoff: BackoffConfig{
+			InitialInterval: 500 * time.Millisecond,
+			MaxInterval:     5 * time.Second,
+			Multiplier:      1.2,
+		},
+	}
+}
+
+// LoadConfig reads the configuration file, if any, and applies the environment variables on top.
+// The file is read once per process, see ResetConfig, the environment is read on every call.
+func LoadConfig() (Config, error) {
+	config, err := loadConfigFile()
+	if err != nil {
+		return config, err
+	}
+	if err := config.applyEnv(); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+type configCache struct {
+	once   sync.Once
+	config Config
+	err    error
+}
+
+var (
+	configMu     sync.Mutex
+	cachedConfig = &configCache{}
+)
+
+// ResetConfig drops the configuration file read by LoadConfig, the next call reads it again,
+// e.g. after a test pointed TESTCONTAINERS_CONFIG to another file
+func ResetConfig() {
+	configMu.Lock()
+	defer configMu.Unlock()
+	cachedConfig = &configCache{}
+}
+
+// loadConfigFile returns a copy of the cached configuration file
+func loadConfigFile() (Config, error) {
+	configMu.Lock()
+	cache := cachedConfig
+	configMu.Unlock()
+
+	cache.once.Do(func() {
+		cache.config, cache.err = readConfigFile()
+	})
+	config := cache.config
+	// applyEnv writes to the modules
+	if cache.config.Modules != nil {
+		config.Modules = make(map[string]ModuleConfig, len(cache.config.Modules))
+		for module, moduleConfig := range cache.config.Modules {
+			config.Modules[module] = moduleConfig
+		}
+	}
+	return config, cache.err
+}
+
+func readConfigFile() (Config, error) {
+	config := defaultConfig()
+	path, err := configFile()
+	if err != nil {
+		return config, err
+	}
+	if path == "" {
+		return config, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, fmt.Errorf("failed to read config: %v", err)
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return config, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+	return config, nil
+}
+
+// Module returns the configuration of a module with the global settings filled in
+func (c Config) Module(module string) ModuleConfig {
+	config := c.Modules[module]
+	if config.StartupTimeout <= 0 {
+		config.StartupTimeout = c.StartupTimeout
+	}
+	if config.LogLevel == "" {
+		config.LogLevel = c.LogLevel
+	}
+	config.InMemoryStorage = config.InMemoryStorage || c.InMemoryStorage
+	return config
+}
+
+// ApplyDefaults loads the configuration and fills the unset options of a module from it
+func ApplyDefaults(module string, options *ContainerOptions) (ModuleConfig, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return ModuleConfig{}, err
+	}
+	defaults := config.Module(module)
+	if options.StartupTimeout <= 0 {
+		options.StartupTimeout = defaults.StartupTimeout
+	}
+	if defaults.InMemoryStorage {
+		options.InMemoryStorage = true
+	}
+	return defaults, nil
+}
+
+// configFile returns the path of the configuration file, empty if there is none
+func configFile() (string, error) {
+	if path := os.Getenv(EnvConfigFile); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("failed to read config: %v", err)
+		}
+		return path, nil
+	}
+	var dirs []string
+	if dir, err := os.Getwd(); err == nil {
+		if root, ok := moduleRoot(dir); ok {
+			dirs = append(dirs, root)
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, home)
+	}
+	for _, dir := range dirs {
+		path := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("failed to read config: %v", err)
+		}
+	}
+	return "", nil
+}
+
+// moduleRoot returns the closest parent of dir containing a go.mod
+func moduleRoot(dir string) (string, bool) {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
+// moduleEnv returns the environment variable of a module setting, e.g. TESTCONTAINERS_MONGO_IMAGE
+func moduleEnv(module, setting string) string {
+	name := strings.ToUpper(unsafeEnvChars.ReplaceAllString(module, "_"))
+	return "TESTCONTAINERS_" + name + "_" + setting
+}
+
+func (c *Config) applyEnv() error {
+	var errs []error
+	env := func(name string, apply func(value string) error) {
+		if value := os.Getenv(name); value != "" {
+			if err := apply(value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid %s: %v", name, err))
+			}
+		}
+	}
+	str := func(dst *string) func(string) error {
+		return func(value string) error {
+			*dst = value
+			return nil
+		}
+	}
+	duration := func(dst *time.Duration) func(string) error {
+		return func(value string) (err error) {
+			*dst, err = time.ParseDuration(value)
+			return err
+		}
+	}
+	boolean := func(dst *bool) func(string) error {
+		return func(value string) (err error) {
+			*dst, err = strconv.ParseBool(value)
+			return err
+		}
+	}
+
+	env("TESTCONTAINERS_STARTUP_TIMEOUT", duration(&c.StartupTimeout))
+	env("TESTCONTAINERS_NETWORK_TIMEOUT", duration(&c.NetworkTimeout))
+	env("TESTCONTAINERS_LOG_LEVEL", str(&c.LogLevel))
+	env("TESTCONTAINERS_REUSE", boolean(&c.Reuse))
+	env("TESTCONTAINERS_KEEP_FAILED", boolean(&c.KeepFailed))
+	env("TESTCONTAINERS_IN_MEMORY_STORAGE", boolean(&c.InMemoryStorage))
+	env(EnvOffline, boolean(&c.Offline))
+	env(EnvImageArchives, str(&c.ImageArchives))
+	env(EnvRegistryMirror, str(&c.RegistryMirror))
+	env("TESTCONTAINERS_BACKOFF_INITIAL_INTERVAL", duration(&c.Backoff.InitialInterval))
+	env("TESTCONTAINERS_BACKOFF_MAX_INTERVAL", duration(&c.Backoff.MaxInterval))
+	env("TESTCONTAINERS_BACKOFF_MULTIPLIER", func(value string) (err error) {
+		c.Backoff.Multiplier, err = strconv.ParseFloat(value, 64)
+		return err
+	})
+
+	modules := CatalogModules()
+	for module := range c.Modules {
+		modules = append(modules, module)
+	}
+	for _, module := range modules {
+		config := c.Modules[module]
+		env(moduleEnv(module, "IMAGE"), str(&config.Image))
+		env(moduleEnv(module, "STARTUP_TIMEOUT"), duration(&config.StartupTimeout))
+		env(moduleEnv(module, "LOG_LEVEL"), str(&config.LogLevel))
+		env(moduleEnv(module, "IN_MEMORY_STORAGE"), boolean(&config.InMemoryStorage))
+		if config != (ModuleConfig{}) {
+			if c.Modules == nil {
+				c.Modules = make(map[string]ModuleConfig)
+			}
+			c.Modules[module] = config
+		}
+	}
+	return errors.Join(errs...)
+}
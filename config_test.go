@@ -0,0 +1,154 @@
+package testcontainers
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// isolateConfig hides the configuration of the machine running the tests: the home directory,
+// the file in the module root and the TESTCONTAINERS_* variables, except those of the reaper
+func isolateConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, "TESTCONTAINERS_") && !strings.HasPrefix(name, "TESTCONTAINERS_RYUK_") {
+			t.Setenv(name, "")
+		}
+	}
+	writeConfig(t, "")
+}
+
+// writeConfig points TESTCONTAINERS_CONFIG to a file with content and drops the cached one
+func writeConfig(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), ConfigFileName)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	t.Setenv(EnvConfigFile, path)
+	ResetConfig()
+	t.Cleanup(ResetConfig)
+}
+
+func TestLoadConfig(t *testing.T) {
+	isolateConfig(t)
+	writeConfig(t, `
+startup_timeout: 2m
+log_level: INFO
+keep_failed: true
+backoff:
+  initial_interval: 100ms
+modules:
+  mongo:
+    image: mongo:7.0
+    startup_timeout: 3m
+  kafka:
+    log_level: DEBUG
+`)
+	t.Setenv("TESTCONTAINERS_LOG_LEVEL", "ERROR")
+	t.Setenv("TESTCONTAINERS_REDIS_IN_MEMORY_STORAGE", "true")
+	t.Setenv(ImageEnv("mongo"), "")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, config.StartupTimeout)
+	require.Equal(t, DefaultNetworkTimeout, config.NetworkTimeout)
+	require.True(t, config.KeepFailed)
+	require.Equal(t, 100*time.Millisecond, config.Backoff.InitialInterval)
+	require.Equal(t, 5*time.Second, config.Backoff.MaxInterval)
+
+	mongo := config.Module("mongo")
+	require.Equal(t, 3*time.Minute, mongo.StartupTimeout)
+	require.Equal(t, "ERROR", mongo.LogLevel)
+	require.Equal(t, "DEBUG", config.Module("kafka").LogLevel)
+	require.True(t, config.Module("redis").InMemoryStorage)
+	require.Equal(t, 2*time.Minute, config.Module("redis").StartupTimeout)
+
+	image, err := Image("mongo", "")
+	require.NoError(t, err)
+	require.Equal(t, "mongo:7.0", image)
+	// the environment wins over the file
+	t.Setenv(ImageEnv("mongo"), "mongo:6.0")
+	image, err = Image("mongo", "")
+	require.NoError(t, err)
+	require.Equal(t, "mongo:6.0", image)
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	isolateConfig(t)
+	writeConfig(t, "startup_timeot: 2m\n")
+	_, err := LoadConfig()
+	require.ErrorContains(t, err, "field startup_timeot not found")
+
+	writeConfig(t, "")
+	t.Setenv("TESTCONTAINERS_STARTUP_TIMEOUT", "2 minutes")
+	t.Setenv("TESTCONTAINERS_REUSE", "sometimes")
+	_, err = LoadConfig()
+	require.ErrorContains(t, err, "invalid TESTCONTAINERS_STARTUP_TIMEOUT")
+	require.ErrorContains(t, err, "invalid TESTCONTAINERS_REUSE")
+
+	t.Setenv(EnvConfigFile, filepath.Join(t.TempDir(), "missing.yaml"))
+	ResetConfig()
+	_, err = LoadConfig()
+	require.Error(t, err)
+}
+
+func TestLoadConfigReadsFileOnce(t *testing.T) {
+	isolateConfig(t)
+	writeConfig(t, "startup_timeout: 2m\nmodules:\n  mongo:\n    image: mongo:7.0\n")
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, config.StartupTimeout)
+
+	require.NoError(t, os.WriteFile(os.Getenv(EnvConfigFile), []byte("startup_timeout: 5m\n"), 0o644))
+	// the environment is still read on every call and does not leak into the cached file
+	t.Setenv("TESTCONTAINERS_MONGO_LOG_LEVEL", "DEBUG")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, config.StartupTimeout)
+	require.Equal(t, "DEBUG", config.Module("mongo").LogLevel)
+	t.Setenv("TESTCONTAINERS_MONGO_LOG_LEVEL", "")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, ModuleConfig{Image: "mongo:7.0", StartupTimeout: 2 * time.Minute}, config.Module("mongo"))
+
+	ResetConfig()
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	require.Equal(t, 5*time.Minute, config.StartupTimeout)
+}
+
+func TestApplyDefaults(t *testing.T) {
+	isolateConfig(t)
+	writeConfig(t, `
+startup_timeout: 2m
+in_memory_storage: true
+modules:
+  kafka:
+    log_level: DEBUG
+`)
+	var options ContainerOptions
+	defaults, err := ApplyDefaults("kafka", &options)
+	require.NoError(t, err)
+	require.Equal(t, "DEBUG", defaults.LogLevel)
+	require.Equal(t, 2*time.Minute, options.StartupTimeout)
+	require.True(t, options.InMemoryStorage)
+
+	// per-call options win
+	options = ContainerOptions{StartupTimeout: time.Second}
+	_, err = ApplyDefaults("kafka", &options)
+	require.NoError(t, err)
+	require.Equal(t, time.Second, options.StartupTimeout)
+}
+
+func TestModuleRoot(t *testing.T) {
+	dir := t.TempDir()
+	nested := filepath.Join(dir, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/a\n"), 0o644))
+	root, ok := moduleRoot(nested)
+	require.True(t, ok)
+	require.Equal(t, dir, root)
+}
@@ -1,75 +1,192 @@
 package testcontainers
 
 import (
-	"os/exec"
-	"runtime"
-	"strings"
+	"context"
+	"errors"
+	"fmt"
 	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 )
 
-func dockerCmd() string {
-	if runtime.GOOS == "windows" {
-		return "docker.exe"
+// NewDockerClient creates a docker engine API client for the current runtime,
+// TLS and the API version are configured from the environment
+func NewDockerClient() (*dockerclient.Client, error) {
+	opts := []dockerclient.Opt{dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation()}
+	if runtime, err := CurrentRuntime(); err == nil {
+		opts = append(opts, dockerclient.WithHost(runtime.Host))
+	}
+	client, err := dockerclient.NewClientWithOpts(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get docker client: %v", err)
 	}
-	return "docker"
+	return client, nil
 }
 
-func DockerExists() bool {
-	out, err := exec.Command(dockerCmd(), "version").Output()
+// DockerExists reports whether the docker daemon is reachable
+func DockerExists(ctx context.Context) bool {
+	client, err := NewDockerClient()
 	if err != nil {
 		return false
 	}
-	cnt := strings.Count(string(out), "Version")
-	return cnt > 0
+	defer client.Close()
+	_, err = client.Ping(ctx)
+	return err == nil
 }
 
-func ContainerExists(name string) (bool, error) {
-	out, err := exec.Command(dockerCmd(), "inspect", "--format=\"{{.Name}}\"", name).Output()
+// ContainerExists reports whether a container with the given name exists
+func ContainerExists(ctx context.Context, name string) (bool, error) {
+	client, err := NewDockerClient()
 	if err != nil {
-		if noSuchContainerErr(err) {
+		return false, err
+	}
+	defer client.Close()
+	inspect, err := client.ContainerInspect(ctx, name)
+	if err != nil {
+		if dockerclient.IsErrNotFound(err) {
 			return false, nil
 		}
-		return false, err
+		return false, fmt.Errorf("failed to inspect container %s: %v", name, err)
+	}
+	return "/"+name == inspect.Name, nil
+}
+
+// DropContainerIfExists force removes a container and its volumes.
+// A container that does not exist is not an error.
+func DropContainerIfExists(ctx context.Context, containerName string) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
 	}
-	containerName := strings.Trim(string(out), "\n")
-	containerName = strings.Trim(containerName, `"`)
-	return "/"+name == containerName, nil
+	defer client.Close()
+	return dropContainer(ctx, client, containerName)
 }
 
-func DropContainerIfExists(containerName string) {
-	for i := 0; i < 3; i++ {
-		out, err := exec.Command(dockerCmd(), "container", "rm", "-f", "/"+containerName).Output()
-		if noSuchContainerErr(err) {
-			return
+// DropNetwork removes a network. A network that does not exist is not an error.
+func DropNetwork(ctx context.Context, networkName string) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.NetworkRemove(ctx, networkName); err != nil && !dockerclient.IsErrNotFound(err) {
+		return fmt.Errorf("failed to remove network %s: %v", networkName, err)
+	}
+	return nil
+}
+
+// PruneNetwork removes the unused networks created by this process. Networks of other
+// processes, e.g. parallel test binaries that have not attached their containers yet, are kept.
+func PruneNetwork(ctx context.Context) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	args := filters.NewArgs(
+		filters.Arg("label", LabelLibrary+"=true"),
+		filters.Arg("label", LabelSessionID+"="+SessionID()),
+	)
+	if _, err := client.NetworksPrune(ctx, args); err != nil {
+		return fmt.Errorf("failed to prune networks: %v", err)
+	}
+	return nil
+}
+
+// DropContainers force removes the given containers and the networks created by the library
+// they were attached to once no container uses them anymore.
+// Every container is attempted, the returned error joins all failures.
+func DropContainers(ctx context.Context, containerNames []string) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	var errs []error
+	var networks []string
+	for i := 0; i < len(containerNames); i++ {
+		if info, err := client.ContainerInspect(ctx, containerNames[i]); err == nil && info.NetworkSettings != nil {
+			for network := range info.NetworkSettings.Networks {
+				networks = append(networks, network)
+			}
 		}
-		if len(out) == 0 {
-			break
+		if err := dropContainer(ctx, client, containerNames[i]); err != nil {
+			errs = append(errs, err)
 		}
-		time.Sleep(time.Second)
 	}
+	errs = append(errs, removeUnusedNetworks(ctx, client, networks))
+	return errors.Join(errs...)
 }
 
-func DropNetwork(networkName string) {
-	_, _ = exec.Command(dockerCmd(), "network", "rm", networkName).Output()
+// networkRemover is the part of the docker client used to remove networks left unused
+type networkRemover interface {
+	NetworkInspect(ctx context.Context, networkID string, options types.NetworkInspectOptions) (types.NetworkResource, error)
+	NetworkRemove(ctx context.Context, networkID string) error
 }
 
-func PruneNetwork() {
-	_, _ = exec.Command(dockerCmd(), "network", "prune").Output()
+// removeUnusedNetworks removes the networks of the library without containers,
+// networks created by others, e.g. bridge, are never removed
+func removeUnusedNetworks(ctx context.Context, client networkRemover, names []string) error {
+	var errs []error
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		network, err := client.NetworkInspect(ctx, name, types.NetworkInspectOptions{})
+		if dockerclient.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to inspect network %s: %v", name, err))
+			continue
+		}
+		if network.Labels[LabelLibrary] != "true" || len(network.Containers) > 0 {
+			continue
+		}
+		if err := client.NetworkRemove(ctx, name); err != nil && !dockerclient.IsErrNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to remove network %s: %v", name, err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-func DropContainers(containerNames []string) {
-	_, _ = exec.Command(dockerCmd(), "network", "-f", "prune").Output()
-	for i := 0; i < len(containerNames); i++ {
-		DropContainerIfExists(containerNames[i])
+// containerRemover is the part of the docker client used to remove containers
+type containerRemover interface {
+	ContainerRemove(ctx context.Context, container string, options types.ContainerRemoveOptions) error
+}
+
+// dropContainer retries transient failures, a container that does not exist is removed already
+func dropContainer(ctx context.Context, client containerRemover, containerName string) error {
+	remove := func() error {
+		err := client.ContainerRemove(ctx, containerName, types.ContainerRemoveOptions{
+			Force:         true,
+			RemoveVolumes: true,
+		})
+		if err == nil || dockerclient.IsErrNotFound(err) {
+			return nil
+		}
+		return permanentRemoveError(err)
+	}
+	bo := backoff.WithMaxRetries(backoff.WithContext(backoff.NewConstantBackOff(time.Second), ctx), 2)
+	if err := backoff.Retry(remove, bo); err != nil {
+		return fmt.Errorf("failed to remove container %s: %v", containerName, err)
 	}
+	return nil
 }
 
-func noSuchContainerErr(err error) bool {
-	if exitErr, ok := err.(*exec.ExitError); ok {
-		msg := string(exitErr.Stderr)
-		if strings.Contains(msg, "Error: No such") {
-			return true
+// permanentRemoveError stops the retries of errors that do not go away. A removal that is
+// already in progress is reported as a conflict, which is retried like an unreachable daemon.
+func permanentRemoveError(err error) error {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if errdefs.IsInvalidParameter(e) || errdefs.IsForbidden(e) || errdefs.IsUnauthorized(e) || errdefs.IsNotImplemented(e) {
+			return backoff.Permanent(err)
 		}
 	}
-	return false
+	return err
 }
@@ -0,0 +1,69 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoveUnusedNetworks(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeNetworkClient()
+	library := map[string]string{LabelLibrary: "true"}
+	for name, labels := range map[string]map[string]string{
+		"bridge":  nil,
+		"unused":  library,
+		"shared":  library,
+		"foreign": {"owner": "someone"},
+	} {
+		_, err := client.NetworkCreate(ctx, name, types.NetworkCreate{Labels: labels})
+		require.NoError(t, err)
+	}
+	// a container of another test still uses the network
+	require.NoError(t, client.NetworkConnect(ctx, "shared", "other-test", &network.EndpointSettings{}))
+
+	err := removeUnusedNetworks(ctx, client, []string{"bridge", "unused", "shared", "foreign", "unused", "gone"})
+	require.NoError(t, err)
+	require.NotContains(t, client.members, "unused")
+	require.Contains(t, client.members, "bridge")
+	require.Contains(t, client.members, "shared")
+	require.Contains(t, client.members, "foreign")
+}
+
+// fakeRemover returns the given errors in order, then nil
+type fakeRemover struct {
+	errs  []error
+	calls int
+}
+
+func (r *fakeRemover) ContainerRemove(context.Context, string, types.ContainerRemoveOptions) error {
+	r.calls++
+	if len(r.errs) == 0 {
+		return nil
+	}
+	err := r.errs[0]
+	r.errs = r.errs[1:]
+	return err
+}
+
+func TestDropContainer(t *testing.T) {
+	ctx := context.Background()
+
+	client := &fakeRemover{errs: []error{errdefs.NotFound(errors.New("no such container"))}}
+	require.NoError(t, dropContainer(ctx, client, "gone"))
+	require.Equal(t, 1, client.calls)
+
+	client = &fakeRemover{errs: []error{errdefs.InvalidParameter(errors.New("bad name"))}}
+	require.ErrorContains(t, dropContainer(ctx, client, "bad"), "bad name")
+	require.Equal(t, 1, client.calls)
+
+	// a removal already in progress is retried
+	client = &fakeRemover{errs: []error{errdefs.Conflict(errors.New("removal in progress"))}}
+	require.NoError(t, dropContainer(ctx, client, "app"))
+	require.Equal(t, 2, client.calls)
+}
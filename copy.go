@@ -0,0 +1,283 @@
+package testcontainers
+
+import (
+	"archive/tar"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// copyClient is the part of the docker client used to copy files into containers
+type copyClient interface {
+	CopyToContainer(ctx context.Context, containerID, dstPath string, content io.Reader, options types.CopyToContainerOptions) error
+}
+
+// CopyDirToContainer copies the content of a host directory to containerDir, which is
+// created if needed. File modes are preserved, files are owned by the container root user.
+func CopyDirToContainer(ctx context.Context, c testcontainers.Container, hostDir, containerDir string) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return copyDirToContainer(ctx, client, c.GetContainerID(), hostDir, containerDir)
+}
+
+// WithDirectory copies a host directory to containerDir after the container
+// is created and before it starts, see CopyDirToContainer
+func WithDirectory(req *testcontainers.ContainerRequest, hostDir, containerDir string) {
+	req.LifecycleHooks = append(req.LifecycleHooks, testcontainers.ContainerLifecycleHooks{
+		PostCreates: []testcontainers.ContainerHook{func(ctx context.Context, c testcontainers.Container) error {
+			return CopyDirToContainer(ctx, c, hostDir, containerDir)
+		}},
+	})
+}
+
+func copyDirToContainer(ctx context.Context, client copyClient, containerID, hostDir, containerDir string) error {
+	if !path.IsAbs(containerDir) {
+		return fmt.Errorf("failed to copy %s: container path %s is not absolute", hostDir, containerDir)
+	}
+	info, err := os.Stat(hostDir)
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %v", hostDir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("failed to copy %s: not a directory", hostDir)
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(writeDirTar(w, hostDir, strings.TrimPrefix(path.Clean(containerDir), "/")))
+	}()
+	defer r.Close()
+	// the archive holds paths relative to the container root
+	if err := client.CopyToContainer(ctx, containerID, "/", r, types.CopyToContainerOptions{}); err != nil {
+		return fmt.Errorf("failed to copy %s to container %s:%s: %v", hostDir, containerID, containerDir, err)
+	}
+	return nil
+}
+
+// writeDirTar writes hostDir as a tar archive with its entries named below prefix
+func writeDirTar(w io.Writer, hostDir, prefix string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(hostDir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(hostDir, file)
+		if err != nil {
+			return err
+		}
+		var link string
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = path.Join(prefix, filepath.ToSlash(rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// CopyFromContainer returns a path of a container, a file or a directory, as a tar stream.
+// The entries are named after the base name of containerPath, like docker cp does.
+func CopyFromContainer(ctx context.Context, c testcontainers.Container, containerPath string) (io.ReadCloser, error) {
+	client, err := NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	r, _, err := client.CopyFromContainer(ctx, c.GetContainerID(), containerPath)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to copy %s from container %s: %v", containerPath, c.GetContainerID(), err)
+	}
+	return &closeBoth{ReadCloser: r, client: client}, nil
+}
+
+type closeBoth struct {
+	io.ReadCloser
+	client io.Closer
+}
+
+func (c *closeBoth) Close() error {
+	return errors.Join(c.ReadCloser.Close(), c.client.Close())
+}
+
+// CopyFromContainerToHost extracts a path of a container into hostDir, e.g. /var/lib/kafka/data
+// ends up in hostDir/data. File modes are preserved.
+func CopyFromContainerToHost(ctx context.Context, c testcontainers.Container, containerPath, hostDir string) error {
+	r, err := CopyFromContainer(ctx, c, containerPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := ExtractTar(r, hostDir); err != nil {
+		return fmt.Errorf("failed to copy %s from container %s: %v", containerPath, c.GetContainerID(), err)
+	}
+	return nil
+}
+
+// ExtractTar extracts a tar stream into dir, which is created if needed.
+// Entries escaping dir, directly or through a symlink of the archive, are rejected,
+// and files already in dir are replaced rather than written through.
+func ExtractTar(r io.Reader, dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tr := tar.NewReader(r)
+	links := make(map[string]bool)
+	// directory modes are set last so that read only directories can be filled
+	dirModes := make(map[string]fs.FileMode)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			for target, mode := range dirModes {
+				if err := os.Chmod(target, mode); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.FromSlash(strings.TrimSuffix(header.Name, "/"))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("tar entry %s escapes %s", header.Name, dir)
+		}
+		if links[name] {
+			return fmt.Errorf("tar entry %s replaces the symlink %s", header.Name, name)
+		}
+		if link, ok := belowLink(links, name); ok {
+			return fmt.Errorf("tar entry %s is below the symlink %s", header.Name, link)
+		}
+		target := filepath.Join(dir, name)
+		if header.Typeflag != tar.TypeDir {
+			if err := removeExisting(target); err != nil {
+				return err
+			}
+		}
+		mode := header.FileInfo().Mode() & (fs.ModePerm | fs.ModeSetuid | fs.ModeSetgid | fs.ModeSticky)
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			dirModes[target] = mode
+			continue
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := extractFile(tr, target); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+			links[name] = true
+			continue
+		case tar.TypeLink:
+			linkname := filepath.FromSlash(header.Linkname)
+			if !filepath.IsLocal(linkname) {
+				return fmt.Errorf("tar entry %s links outside of %s", header.Name, dir)
+			}
+			if link, ok := belowLink(links, linkname); ok {
+				return fmt.Errorf("tar entry %s links below the symlink %s", header.Name, link)
+			}
+			if links[linkname] {
+				return fmt.Errorf("tar entry %s links to the symlink %s", header.Name, linkname)
+			}
+			if err := os.Link(filepath.Join(dir, linkname), target); err != nil {
+				return err
+			}
+			continue
+		default:
+			// devices and fifos are not needed to inspect data
+			continue
+		}
+		if err := os.Chmod(target, mode); err != nil {
+			return err
+		}
+	}
+}
+
+// belowLink returns the symlink among the parents of name
+func belowLink(links map[string]bool, name string) (string, bool) {
+	for parent := filepath.Dir(name); parent != "."; parent = filepath.Dir(parent) {
+		if links[parent] {
+			return parent, true
+		}
+	}
+	return "", false
+}
+
+// removeExisting removes the file or symlink at target, a symlink created on the host
+// or earlier in the archive would otherwise be followed when target is written
+func removeExisting(target string) error {
+	info, err := os.Lstat(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	return os.Remove(target)
+}
+
+func extractFile(r io.Reader, target string) error {
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
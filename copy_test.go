@@ -0,0 +1,128 @@
+package testcontainers
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCopyClient keeps the archive copied to the container
+type fakeCopyClient struct {
+	dstPath string
+	archive bytes.Buffer
+}
+
+func (c *fakeCopyClient) CopyToContainer(_ context.Context, _, dstPath string, content io.Reader, _ types.CopyToContainerOptions) error {
+	c.dstPath = dstPath
+	_, err := io.Copy(&c.archive, content)
+	return err
+}
+
+func TestCopyDirToContainer(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "certs"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "certs", "server.key"), []byte("key"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "init.sh"), []byte("#!/bin/sh"), 0o755))
+	require.NoError(t, os.Symlink("init.sh", filepath.Join(src, "start.sh")))
+
+	client := &fakeCopyClient{}
+	require.NoError(t, copyDirToContainer(context.Background(), client, "id", src, "/etc/seed/"))
+	require.Equal(t, "/", client.dstPath)
+
+	names := make(map[string]*tar.Header)
+	tr := tar.NewReader(bytes.NewReader(client.archive.Bytes()))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		names[header.Name] = header
+	}
+	require.Len(t, names, 5)
+	require.Equal(t, int64(0o700), names["etc/seed/certs/"].Mode&0o777)
+	require.Equal(t, int64(0o600), names["etc/seed/certs/server.key"].Mode&0o777)
+	require.Equal(t, int64(0o755), names["etc/seed/init.sh"].Mode&0o777)
+	require.Equal(t, "init.sh", names["etc/seed/start.sh"].Linkname)
+	require.Contains(t, names, "etc/seed/")
+
+	// extracting the archive restores the tree and its modes
+	dst := t.TempDir()
+	require.NoError(t, ExtractTar(bytes.NewReader(client.archive.Bytes()), dst))
+	info, err := os.Stat(filepath.Join(dst, "etc", "seed", "certs", "server.key"))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	data, err := os.ReadFile(filepath.Join(dst, "etc", "seed", "start.sh"))
+	require.NoError(t, err)
+	require.Equal(t, "#!/bin/sh", string(data))
+
+	require.Error(t, copyDirToContainer(context.Background(), client, "id", src, "etc/seed"))
+	require.Error(t, copyDirToContainer(context.Background(), client, "id", filepath.Join(src, "init.sh"), "/etc/seed"))
+}
+
+func TestExtractTarRejectsEscapes(t *testing.T) {
+	archive := func(headers ...*tar.Header) io.Reader {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, header := range headers {
+			require.NoError(t, tw.WriteHeader(header))
+		}
+		require.NoError(t, tw.Close())
+		return &buf
+	}
+
+	err := ExtractTar(archive(&tar.Header{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0o644}), t.TempDir())
+	require.ErrorContains(t, err, "escapes")
+
+	err = ExtractTar(archive(
+		&tar.Header{Name: "data/link", Typeflag: tar.TypeSymlink, Linkname: "/etc"},
+		&tar.Header{Name: "data/link/passwd", Typeflag: tar.TypeReg, Mode: 0o644},
+	), t.TempDir())
+	require.ErrorContains(t, err, "below the symlink")
+
+	err = ExtractTar(archive(&tar.Header{Name: "data/hard", Typeflag: tar.TypeLink, Linkname: "../../etc/passwd"}), t.TempDir())
+	require.ErrorContains(t, err, "links outside")
+
+	// a file with the name of a symlink must not be written through it
+	outside := t.TempDir()
+	victim := filepath.Join(outside, "victim")
+	require.NoError(t, os.WriteFile(victim, []byte("safe"), 0o644))
+	err = ExtractTar(archive(
+		&tar.Header{Name: "x", Typeflag: tar.TypeSymlink, Linkname: victim},
+		&tar.Header{Name: "x", Typeflag: tar.TypeReg, Mode: 0o644},
+	), t.TempDir())
+	require.ErrorContains(t, err, "replaces the symlink")
+	data, err := os.ReadFile(victim)
+	require.NoError(t, err)
+	require.Equal(t, "safe", string(data))
+
+	err = ExtractTar(archive(
+		&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+		&tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "link"},
+	), t.TempDir())
+	require.ErrorContains(t, err, "links to the symlink")
+
+	err = ExtractTar(archive(
+		&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: outside},
+		&tar.Header{Name: "hard", Typeflag: tar.TypeLink, Linkname: "link/victim"},
+	), t.TempDir())
+	require.ErrorContains(t, err, "links below the symlink")
+
+	// a symlink already in the directory is replaced
+	dir := t.TempDir()
+	require.NoError(t, os.Symlink(victim, filepath.Join(dir, "x")))
+	require.NoError(t, ExtractTar(archive(&tar.Header{Name: "x", Typeflag: tar.TypeReg, Mode: 0o644}), dir))
+	data, err = os.ReadFile(victim)
+	require.NoError(t, err)
+	require.Equal(t, "safe", string(data))
+	info, err := os.Lstat(filepath.Join(dir, "x"))
+	require.NoError(t, err)
+	require.True(t, info.Mode().IsRegular())
+}
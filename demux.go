@@ -0,0 +1,280 @@
+package testcontainers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// StreamType identifies a stream in docker's multiplexed attach protocol
+type StreamType byte
+
+const (
+	Stdin StreamType = iota
+	Stdout
+	Stderr
+	// Systemerr carries errors reported by the docker daemon itself
+	Systemerr
+)
+
+func (t StreamType) String() string {
+	switch t {
+	case Stdin:
+		return "stdin"
+	case Stdout:
+		return "stdout"
+	case Stderr:
+		return "stderr"
+	case Systemerr:
+		return "systemerr"
+	}
+	return fmt.Sprintf("stream(%d)", byte(t))
+}
+
+const (
+	demuxHeaderLen = 8
+	demuxChunkLen  = 32 * 1024
+	streamCount    = int(Systemerr) + 1
+)
+
+// ErrTruncatedFrame is returned when the stream ends in the middle of a frame
+var ErrTruncatedFrame = errors.New("truncated docker stream frame")
+
+// Demuxer splits the output of an exec or attach call into its streams.
+//
+// Without a TTY docker multiplexes the streams into frames of
+// [8]byte{STREAM_TYPE, 0, 0, 0, SIZE1, SIZE2, SIZE3, SIZE4} followed by SIZE bytes of payload,
+// see https://docs.docker.com/engine/api/v1.26/#tag/Container/operation/ContainerAttach.
+// With a TTY the output is sent raw and is delivered as stdout.
+//
+// Frames of a stream that is not being read are buffered in memory,
+// frames of unknown stream types are discarded.
+type Demuxer struct {
+	mu       sync.Mutex
+	r        io.Reader
+	detected bool
+	tty      bool
+	stream   StreamType
+	remain   uint32
+	header   [demuxHeaderLen]byte
+	chunk    []byte
+	buffered [streamCount]bytes.Buffer
+	err      error
+}
+
+// NewDemuxer creates a demuxer that detects from the first bytes
+// whether the output is multiplexed or raw TTY output
+func NewDemuxer(r io.Reader) *Demuxer {
+	return &Demuxer{r: r}
+}
+
+// NewTTYDemuxer creates a demuxer for raw TTY output
+func NewTTYDemuxer(r io.Reader) *Demuxer {
+	return &Demuxer{r: r, detected: true, tty: true}
+}
+
+// Stream returns a reader for a single stream
+func (d *Demuxer) Stream(t StreamType) *DemuxStream {
+	return &DemuxStream{d: d, t: t}
+}
+
+// Stdout returns a reader for the standard output
+func (d *Demuxer) Stdout() *DemuxStream {
+	return d.Stream(Stdout)
+}
+
+// Stderr returns a reader for the standard error
+func (d *Demuxer) Stderr() *DemuxStream {
+	return d.Stream(Stderr)
+}
+
+// Copy writes the standard output and error to the given writers
+// in the order they were produced until the underlying reader is drained.
+// A nil writer discards the stream.
+func (d *Demuxer) Copy(stdout, stderr io.Writer) (written int64, err error) {
+	var writers [streamCount]io.Writer
+	writers[Stdout] = stdout
+	writers[Stderr] = stderr
+	return d.copy(writers)
+}
+
+func (d *Demuxer) copy(writers [streamCount]io.Writer) (written int64, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// drain what a stream reader buffered before
+	for t := range d.buffered {
+		if writers[t] == nil {
+			d.buffered[t].Reset()
+			continue
+		}
+		n, err := d.buffered[t].WriteTo(writers[t])
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	for {
+		t, p, err := d.next()
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+		if w := writers[t]; w != nil {
+			n, err := w.Write(p)
+			written += int64(n)
+			if err != nil {
+				return written, err
+			}
+		}
+	}
+}
+
+func (d *Demuxer) read(t StreamType, p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for {
+		if int(t) < streamCount && d.buffered[t].Len() > 0 {
+			return d.buffered[t].Read(p)
+		}
+		next, chunk, err := d.next()
+		if err != nil {
+			return 0, err
+		}
+		if next == t && len(chunk) <= len(p) {
+			return copy(p, chunk), nil
+		}
+		d.buffered[next].Write(chunk)
+	}
+}
+
+// next returns the next piece of payload of at most demuxChunkLen bytes.
+// The returned slice is only valid until the next call.
+func (d *Demuxer) next() (StreamType, []byte, error) {
+	if d.err != nil {
+		return 0, nil, d.err
+	}
+	if d.chunk == nil {
+		d.chunk = make([]byte, demuxChunkLen)
+	}
+	if !d.detected {
+		if err := d.detect(); err != nil {
+			d.err = err
+			return 0, nil, err
+		}
+		if d.tty && d.remain > 0 {
+			// the bytes used for detection are the start of the raw output
+			p := d.chunk[:d.remain]
+			copy(p, d.header[:d.remain])
+			d.remain = 0
+			return Stdout, p, nil
+		}
+	}
+	if d.tty {
+		for {
+			n, err := d.r.Read(d.chunk)
+			if n > 0 {
+				return Stdout, d.chunk[:n], nil
+			}
+			if err != nil {
+				d.err = err
+				return 0, nil, err
+			}
+		}
+	}
+	for {
+		for d.remain == 0 {
+			n, err := io.ReadFull(d.r, d.header[:])
+			if err != nil {
+				if err == io.ErrUnexpectedEOF || (err == io.EOF && n > 0) {
+					err = ErrTruncatedFrame
+				}
+				d.err = err
+				return 0, nil, err
+			}
+			d.stream = StreamType(d.header[0])
+			d.remain = binary.BigEndian.Uint32(d.header[4:])
+		}
+		size := d.remain
+		if size > demuxChunkLen {
+			size = demuxChunkLen
+		}
+		n, err := io.ReadFull(d.r, d.chunk[:size])
+		d.remain -= uint32(n)
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = ErrTruncatedFrame
+			}
+			d.err = err
+			return 0, nil, err
+		}
+		// the payload of unknown stream types is consumed but dropped
+		if int(d.stream) < streamCount {
+			return d.stream, d.chunk[:n], nil
+		}
+	}
+}
+
+// detect reads the first header and decides whether the output is multiplexed
+func (d *Demuxer) detect() error {
+	d.detected = true
+	n, err := io.ReadFull(d.r, d.header[:])
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	if n < demuxHeaderLen || !validHeader(d.header) {
+		// too short or no frame header, the bytes are raw output
+		d.tty = true
+		d.remain = uint32(n)
+		return nil
+	}
+	d.stream = StreamType(d.header[0])
+	d.remain = binary.BigEndian.Uint32(d.header[4:])
+	return nil
+}
+
+func validHeader(header [demuxHeaderLen]byte) bool {
+	return int(header[0]) < streamCount && header[1] == 0 && header[2] == 0 && header[3] == 0
+}
+
+// DemuxStream reads a single stream of a Demuxer
+type DemuxStream struct {
+	d *Demuxer
+	t StreamType
+}
+
+// Read implements io.Reader
+func (s *DemuxStream) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	return s.d.read(s.t, p)
+}
+
+// WriteTo implements io.WriterTo
+func (s *DemuxStream) WriteTo(w io.Writer) (written int64, err error) {
+	buf := make([]byte, demuxChunkLen)
+	for {
+		n, err := s.Read(buf)
+		if n > 0 {
+			nw, werr := w.Write(buf[:n])
+			written += int64(nw)
+			if werr != nil {
+				return written, werr
+			}
+		}
+		if err == io.EOF {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package testcontainers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func frame(t StreamType, payload string) []byte {
+	header := make([]byte, demuxHeaderLen)
+	header[0] = byte(t)
+	binary.BigEndian.PutUint32(header[4:], uint32(len(payload)))
+	return append(header, payload...)
+}
+
+func readRecorded(t testing.TB, name string) []byte {
+	b, err := os.ReadFile(filepath.Join("testdata", "demux", name))
+	require.NoError(t, err)
+	return b
+}
+
+func TestDemuxerRecorded(t *testing.T) {
+	testCases := []struct {
+		file   string
+		stdout string
+		stderr string
+	}{
+		{
+			file:   "kafka-version.bin",
+			stdout: "7.3.0-ccs (Commit: 9aeaf2e5e4ba3ed1)\n",
+		},
+		{
+			file:   "mongosh-rs-status.bin",
+			stdout: "{\n  set: 'rs0',\n  members: [ { name: 'master:27017', stateStr: 'PRIMARY' } ],\n  ok: 1\n}\n",
+			stderr: "Warning: deprecated option\n",
+		},
+		{
+			file:   "unknown-stream.bin",
+			stdout: "before\nafter\n",
+			stderr: "err\n",
+		},
+		{
+			file:   "tty.bin",
+			stdout: "\x1b[32mready\x1b[0m\r\nprompt> ",
+		},
+		{
+			file:   "empty-frames.bin",
+			stdout: "x",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			recorded := readRecorded(t, tc.file)
+
+			// one byte at a time simulates a slow pipe
+			output, err := ReadCmdOutput(iotest.OneByteReader(bytes.NewReader(recorded)))
+			require.NoError(t, err)
+			require.Equal(t, tc.stdout, output.Stdout)
+			require.Equal(t, tc.stderr, output.Stderr)
+
+			demuxer := NewDemuxer(iotest.HalfReader(bytes.NewReader(recorded)))
+			stdout, err := io.ReadAll(demuxer.Stdout())
+			require.NoError(t, err)
+			require.Equal(t, tc.stdout, string(stdout))
+			var stderr bytes.Buffer
+			_, err = demuxer.Stderr().WriteTo(&stderr)
+			require.NoError(t, err)
+			require.Equal(t, tc.stderr, stderr.String())
+		})
+	}
+}
+
+func TestDemuxerTruncated(t *testing.T) {
+	full := frame(Stdout, "hello world")
+	for _, cut := range []int{3, demuxHeaderLen + 4} {
+		output, err := ReadCmdOutput(bytes.NewReader(append(frame(Stderr, "ok"), full[:cut]...)))
+		require.ErrorIs(t, err, ErrTruncatedFrame)
+		require.Equal(t, "ok", output.Stderr)
+	}
+}
+
+func TestDemuxerTTY(t *testing.T) {
+	// a frame header looks like any other output in tty mode
+	raw := string(frame(Stdout, "x"))
+	stdout, err := io.ReadAll(NewTTYDemuxer(bytes.NewReader([]byte(raw))).Stdout())
+	require.NoError(t, err)
+	require.Equal(t, raw, string(stdout))
+
+	// short raw output is not mistaken for a truncated header
+	output, err := ReadCmdOutput(bytes.NewReader([]byte("ok\n")))
+	require.NoError(t, err)
+	require.Equal(t, "ok\n", output.Stdout)
+}
+
+func TestDemuxerLargeFrame(t *testing.T) {
+	payload := string(bytes.Repeat([]byte("a"), 3*demuxChunkLen+17))
+	output, err := ReadCmdOutput(iotest.HalfReader(bytes.NewReader(frame(Stdout, payload))))
+	require.NoError(t, err)
+	require.Equal(t, payload, output.Stdout)
+}
+
+func FuzzDemuxer(f *testing.F) {
+	for _, name := range []string{
+		"kafka-version.bin",
+		"mongosh-rs-status.bin",
+		"unknown-stream.bin",
+		"tty.bin",
+		"empty-frames.bin",
+	} {
+		f.Add(readRecorded(f, name))
+	}
+	f.Add(frame(Systemerr, "daemon error"))
+	f.Add([]byte{})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var stdout, stderr bytes.Buffer
+		_, err := NewDemuxer(bytes.NewReader(data)).Copy(&stdout, &stderr)
+
+		// short reads must not change the result
+		slow, slowErr := ReadCmdOutput(iotest.OneByteReader(bytes.NewReader(data)))
+		require.Equal(t, err, slowErr)
+		require.Equal(t, stdout.String(), slow.Stdout)
+		require.Equal(t, stderr.String(), slow.Stderr)
+
+		// reading the streams one after another yields the same output
+		demuxer := NewDemuxer(bytes.NewReader(data))
+		streamOut, streamErr := io.ReadAll(demuxer.Stdout())
+		if streamErr == nil {
+			streamErrOut, err := io.ReadAll(demuxer.Stderr())
+			require.NoError(t, err)
+			require.Equal(t, stderr.String(), string(streamErrOut))
+			require.Equal(t, stdout.String(), string(streamOut))
+		} else {
+			require.Equal(t, err, streamErr)
+		}
+	})
+}
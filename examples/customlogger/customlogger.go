@@ -19,20 +19,14 @@ func main() {
 	defer container.Terminate(context.Background())
 
 	// start logger
-	logger, err := tc.StartLogger(context.Background(), container.Container)
+	customSink := tc.LogSinkFunc(func(line tc.LogLine) {
+		log.Printf("custom log: %s", line)
+	})
+	logger, err := tc.StartLogger(context.Background(), container.Container, tc.WithLogSink(customSink))
 	if err != nil {
 		log.Fatalf("failed to start logger: %v", err)
 	}
 	defer logger.Stop()
-	go func() {
-		for {
-			message, ok := <-logger.LogChan
-			if !ok {
-				return
-			}
-			log.Printf("custom log: %s", string(message.Content))
-		}
-	}()
 
 	// collect logs for 4 seconds
 	time.Sleep(4 * time.Second)
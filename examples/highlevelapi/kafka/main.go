@@ -16,10 +16,13 @@ func main() {
 	kafkaContainerName := "infra-01-kafka-container"
 	zookeeperContainerName := "infra-01-zookeeper-container"
 
-	testcontainers.DropContainers([]string{
+	err := testcontainers.DropContainers(ctx, []string{
 		kafkaContainerName,
 		zookeeperContainerName,
 	})
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	broker, terminate, err := tcinfra.Kafka(ctx,
 		tcinfra.KafkaContainerName(kafkaContainerName),
@@ -33,11 +36,11 @@ func main() {
 	// consuming, producing
 	_ = broker.Addr
 
-	kafkaExists, err := testcontainers.ContainerExists(kafkaContainerName)
+	kafkaExists, err := testcontainers.ContainerExists(ctx, kafkaContainerName)
 	if err != nil {
 		log.Fatal(err)
 	}
-	zooExists, err := testcontainers.ContainerExists(zookeeperContainerName)
+	zooExists, err := testcontainers.ContainerExists(ctx, zookeeperContainerName)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -13,7 +13,9 @@ func main() {
 	mongoContainerName := "mongo-01-test"
 	mongoContainerPort := 2189
 
-	testcontainers.DropContainerIfExists(mongoContainerName)
+	if err := testcontainers.DropContainerIfExists(ctx, mongoContainerName); err != nil {
+		log.Fatal(err)
+	}
 
 	db, terminate, err := tcinfra.Mongo(ctx,
 		tcinfra.MongoContainerName(mongoContainerName),
@@ -26,5 +28,7 @@ func main() {
 	// your testing logic ...
 	_ = db
 
-	terminate()
+	if err := terminate(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -17,5 +17,7 @@ func main() {
 	// your testing logic ...
 	_ = db
 
-	terminate()
+	if err := terminate(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -14,7 +14,9 @@ func main() {
 	redisContainerName := "redis-01-test"
 	redisContainerPort := 6718
 
-	testcontainers.DropContainerIfExists(redisContainerName)
+	if err := testcontainers.DropContainerIfExists(ctx, redisContainerName); err != nil {
+		log.Fatal(err)
+	}
 
 	db, terminate, err := tcinfra.Redis(ctx,
 		tcinfra.RedisContainerName(redisContainerName),
@@ -30,5 +32,7 @@ func main() {
 
 	fmt.Println("value", "==", cmd.Val())
 
-	terminate()
+	if err := terminate(); err != nil {
+		log.Fatal(err)
+	}
 }
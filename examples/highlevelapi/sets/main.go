@@ -13,14 +13,25 @@ func main() {
 	myInfra := infra.NewSets()
 	defer myInfra.Close()
 
+	ctx := context.Background()
+
 	// reset containers if needed
-	testcontainers.DropNetwork(myInfra.ContainerNames.Network)
-	testcontainers.DropContainerIfExists(myInfra.ContainerNames.Redis)
-	testcontainers.DropContainerIfExists(myInfra.ContainerNames.Mongo)
-	testcontainers.DropContainerIfExists(myInfra.ContainerNames.Kafka)
-	testcontainers.DropContainerIfExists(myInfra.ContainerNames.Zookeeper)
+	if err := testcontainers.DropNetwork(ctx, myInfra.ContainerNames.Network); err != nil {
+		log.Fatal(err)
+	}
+	if err := testcontainers.DropContainerIfExists(ctx, myInfra.ContainerNames.Redis); err != nil {
+		log.Fatal(err)
+	}
+	if err := testcontainers.DropContainerIfExists(ctx, myInfra.ContainerNames.Mongo); err != nil {
+		log.Fatal(err)
+	}
+	if err := testcontainers.DropContainerIfExists(ctx, myInfra.ContainerNames.Kafka); err != nil {
+		log.Fatal(err)
+	}
+	if err := testcontainers.DropContainerIfExists(ctx, myInfra.ContainerNames.Zookeeper); err != nil {
+		log.Fatal(err)
+	}
 
-	ctx := context.Background()
 	myInfra.SetupBridgeNetwork(ctx)
 	myInfra.SetupRedis(ctx)
 	// myInfra.SetupMongo(ctx)
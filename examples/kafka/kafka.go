@@ -24,19 +24,17 @@ func main() {
 
 	// start logger
 	if false {
-		kafkaLogger, err := tc.StartLogger(ctx, container.Kafka.Container)
+		kafkaLogger, err := tc.StartLogger(ctx, container.Kafka.Container, tc.WithLogSink(tc.StdoutSink()))
 		if err != nil {
 			log.Printf("failed to start logger for kafka: %v", err)
 		} else {
 			defer kafkaLogger.Stop()
-			go kafkaLogger.LogToStdout()
 		}
-		zkLogger, err := tc.StartLogger(ctx, container.Zookeeper.Container)
+		zkLogger, err := tc.StartLogger(ctx, container.Zookeeper.Container, tc.WithLogSink(tc.StdoutSink()))
 		if err != nil {
 			log.Printf("failed to start logger for zookeeper: %v", err)
 		} else {
 			defer zkLogger.Stop()
-			go zkLogger.LogToStdout()
 		}
 	}
 
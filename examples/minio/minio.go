@@ -23,12 +23,11 @@ func main() {
 	defer container.Terminate(context.Background())
 
 	// start logger
-	logger, err := tc.StartLogger(context.Background(), container.Container)
+	logger, err := tc.StartLogger(context.Background(), container.Container, tc.WithLogSink(tc.StdoutSink()))
 	if err != nil {
 		log.Printf("failed to start logger: %v", err)
 	} else {
 		defer logger.Stop()
-		go logger.LogToStdout()
 	}
 
 	// connect to minio
@@ -57,7 +57,7 @@ func (s *someTestSuite) SetupSuite() {
 }
 
 func (s *someTestSuite) TearDownSuite() {
-	s.infra.Close()
+	require.NoError(s.T(), s.infra.Close())
 }
 
 func (s *someTestSuite) SetupTest() {
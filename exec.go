@@ -0,0 +1,245 @@
+package testcontainers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// ExitCode is the exit status of a command executed in a container
+type ExitCode int
+
+// Success reports whether the command exited with status 0
+func (c ExitCode) Success() bool {
+	return c == 0
+}
+
+// ExitError is returned by ExecStream when a command exits with a non-zero status
+type ExitError struct {
+	Cmd       []string
+	Container string
+	Code      ExitCode
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("running %s in %s failed: exit code %d", e.Cmd, e.Container, e.Code)
+}
+
+// ExecOptions configures a command executed by ExecStream
+type ExecOptions struct {
+	Env        map[string]string
+	WorkingDir string
+	// User runs the command as user or uid[:gid] instead of the container user
+	User string
+	// Stdin is copied to the command's standard input until it returns io.EOF
+	Stdin io.Reader
+	// Tty allocates a pseudo terminal, stderr is then merged into stdout
+	Tty    bool
+	Stdout io.Writer
+	Stderr io.Writer
+	// OnStdout and OnStderr are called for every line as soon as it is printed
+	OnStdout func(line string)
+	OnStderr func(line string)
+}
+
+func (o ExecOptions) env() []string {
+	env := make([]string, 0, len(o.Env))
+	for k, v := range o.Env {
+		env = append(env, k+"="+v)
+	}
+	sort.Strings(env)
+	return env
+}
+
+// pidWrapper records the pid of the executed command so it can be killed
+// once the context is cancelled, docker has no api to stop an exec instance
+const pidWrapper = `echo $$ > "$0"; exec "$@"`
+
+// shell runs pidWrapper and the kill
+const shell = "/bin/sh"
+
+// execClient is the part of the docker client used by ExecStream
+type execClient interface {
+	ContainerExecCreate(ctx context.Context, container string, config types.ExecConfig) (types.IDResponse, error)
+	ContainerExecAttach(ctx context.Context, execID string, config types.ExecStartCheck) (types.HijackedResponse, error)
+	ContainerExecInspect(ctx context.Context, execID string) (types.ContainerExecInspect, error)
+	ContainerExecStart(ctx context.Context, execID string, config types.ExecStartCheck) error
+	ContainerStatPath(ctx context.Context, containerID, path string) (types.ContainerPathStat, error)
+}
+
+// ExecStream executes a command in a container and delivers its output live.
+// The command is killed when ctx is cancelled, unless the image has no /bin/sh to do so,
+// then it keeps running in the container after ExecStream returned.
+// A non-zero exit status is reported as *ExitError.
+func ExecStream(ctx context.Context, container testcontainers.Container, cmd []string, options ExecOptions) (ExitCode, error) {
+	client, err := NewDockerClient()
+	if err != nil {
+		name, _ := container.Name(ctx)
+		return -1, fmt.Errorf("running %s in %s (%s) failed: %w", cmd, name, container.GetContainerID(), err)
+	}
+	defer client.Close()
+	return execStream(ctx, client, container, cmd, options)
+}
+
+func execStream(ctx context.Context, client execClient, container testcontainers.Container, cmd []string, options ExecOptions) (ExitCode, error) {
+	name, _ := container.Name(ctx)
+	id := container.GetContainerID()
+	wrapErr := func(err error) error {
+		return fmt.Errorf("running %s in %s (%s) failed: %w", cmd, name, id, err)
+	}
+
+	// the pid file is only written when the command can be killed
+	var pidFile string
+	execCmd := cmd
+	if _, err := client.ContainerStatPath(ctx, id, shell); err == nil {
+		pidFile = "/tmp/testcontainers-exec-" + UniqueID() + ".pid"
+		execCmd = append([]string{shell, "-c", pidWrapper, pidFile}, cmd...)
+	}
+	exec, err := client.ContainerExecCreate(ctx, id, types.ExecConfig{
+		User:         options.User,
+		Tty:          options.Tty,
+		AttachStdin:  options.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+		Env:          options.env(),
+		WorkingDir:   options.WorkingDir,
+		Cmd:          execCmd,
+	})
+	if err != nil {
+		return -1, wrapErr(err)
+	}
+	resp, err := client.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{Tty: options.Tty})
+	if err != nil {
+		return -1, wrapErr(err)
+	}
+	defer resp.Close()
+
+	if options.Stdin != nil {
+		go func() {
+			_, _ = io.Copy(resp.Conn, options.Stdin)
+			_ = resp.CloseWrite()
+		}()
+	}
+
+	stdout := newLineWriter(options.Stdout, options.OnStdout)
+	stderr := newLineWriter(options.Stderr, options.OnStderr)
+	done := make(chan error, 1)
+	go func() {
+		demuxer := NewDemuxer(resp.Reader)
+		if options.Tty {
+			demuxer = NewTTYDemuxer(resp.Reader)
+		}
+		_, err := demuxer.Copy(stdout, stderr)
+		stdout.Flush()
+		stderr.Flush()
+		done <- err
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		// the output is not delivered anymore once ExecStream returned
+		resp.Close()
+		<-done
+		if pidFile != "" {
+			killExec(client, id, options.User, pidFile)
+		}
+		return -1, wrapErr(ctx.Err())
+	}
+	if err != nil {
+		return -1, wrapErr(err)
+	}
+
+	inspect, err := client.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return -1, wrapErr(err)
+	}
+	if pidFile != "" {
+		removePidFile(ctx, client, id, options.User, pidFile)
+	}
+
+	code := ExitCode(inspect.ExitCode)
+	if !code.Success() {
+		return code, &ExitError{Cmd: cmd, Container: name, Code: code}
+	}
+	return code, nil
+}
+
+// killExec runs as the user of the command, which may not be allowed to signal it otherwise,
+// and uses its own context since the caller's one is already done
+func killExec(client execClient, containerID, user, pidFile string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	exec, err := client.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		User: user,
+		Cmd:  []string{shell, "-c", `kill -9 "$(cat "$0")"; rm -f "$0"`, pidFile},
+	})
+	if err != nil {
+		return
+	}
+	_ = client.ContainerExecStart(ctx, exec.ID, types.ExecStartCheck{})
+}
+
+func removePidFile(ctx context.Context, client execClient, containerID, user, pidFile string) {
+	exec, err := client.ContainerExecCreate(ctx, containerID, types.ExecConfig{
+		User: user,
+		Cmd:  []string{"rm", "-f", pidFile},
+	})
+	if err != nil {
+		return
+	}
+	_ = client.ContainerExecStart(ctx, exec.ID, types.ExecStartCheck{Detach: true})
+}
+
+// lineWriter forwards everything to an optional writer and
+// calls an optional callback for every complete line
+type lineWriter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	onLine func(line string)
+	buf    bytes.Buffer
+}
+
+func newLineWriter(w io.Writer, onLine func(line string)) *lineWriter {
+	return &lineWriter{w: w, onLine: onLine}
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if lw.w != nil {
+		if _, err := lw.w.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	if lw.onLine == nil {
+		return len(p), nil
+	}
+	lw.buf.Write(p)
+	for {
+		i := bytes.IndexByte(lw.buf.Bytes(), '\n')
+		if i < 0 {
+			break
+		}
+		line := lw.buf.Next(i + 1)
+		lw.onLine(string(bytes.TrimRight(line, "\r\n")))
+	}
+	return len(p), nil
+}
+
+// Flush delivers a trailing line that was not terminated by a newline
+func (lw *lineWriter) Flush() {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+	if lw.onLine != nil && lw.buf.Len() > 0 {
+		lw.onLine(string(bytes.TrimRight(lw.buf.Bytes(), "\r\n")))
+		lw.buf.Reset()
+	}
+}
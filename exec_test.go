@@ -0,0 +1,175 @@
+package testcontainers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestLineWriter(t *testing.T) {
+	var lines []string
+	var raw strings.Builder
+	lw := newLineWriter(&raw, func(line string) {
+		lines = append(lines, line)
+	})
+
+	for _, chunk := range []string{"fir", "st\nsecond\r\nthi", "rd"} {
+		n, err := lw.Write([]byte(chunk))
+		require.NoError(t, err)
+		require.Equal(t, len(chunk), n)
+	}
+	require.Equal(t, []string{"first", "second"}, lines)
+
+	lw.Flush()
+	require.Equal(t, []string{"first", "second", "third"}, lines)
+	require.Equal(t, "first\nsecond\r\nthird", raw.String())
+}
+
+func TestExecOptionsEnv(t *testing.T) {
+	options := ExecOptions{Env: map[string]string{"B": "2", "A": "1"}}
+	require.Equal(t, []string{"A=1", "B=2"}, options.env())
+}
+
+func TestExitCode(t *testing.T) {
+	require.True(t, ExitCode(0).Success())
+	require.False(t, ExitCode(137).Success())
+
+	err := &ExitError{Cmd: []string{"false"}, Container: "test", Code: 1}
+	require.Contains(t, err.Error(), "exit code 1")
+}
+
+// fakeExecClient replays output for every exec, a blocking exec only ends when its connection is closed
+type fakeExecClient struct {
+	mu       sync.Mutex
+	created  []types.ExecConfig
+	started  []types.ExecConfig
+	output   []byte
+	block    bool
+	exitCode int
+	noShell  bool
+}
+
+func (c *fakeExecClient) ContainerStatPath(context.Context, string, string) (types.ContainerPathStat, error) {
+	if c.noShell {
+		return types.ContainerPathStat{}, errors.New("no such file")
+	}
+	return types.ContainerPathStat{Name: "sh"}, nil
+}
+
+func (c *fakeExecClient) ContainerExecCreate(_ context.Context, _ string, config types.ExecConfig) (types.IDResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.created = append(c.created, config)
+	return types.IDResponse{ID: strconv.Itoa(len(c.created) - 1)}, nil
+}
+
+func (c *fakeExecClient) ContainerExecAttach(context.Context, string, types.ExecStartCheck) (types.HijackedResponse, error) {
+	local, remote := net.Pipe()
+	var r io.Reader = bytes.NewReader(c.output)
+	if c.block {
+		// reading the remote end returns io.EOF once the response is closed
+		r = io.MultiReader(r, remote)
+	}
+	return types.HijackedResponse{Conn: local, Reader: bufio.NewReader(r)}, nil
+}
+
+func (c *fakeExecClient) ContainerExecInspect(context.Context, string) (types.ContainerExecInspect, error) {
+	return types.ContainerExecInspect{ExitCode: c.exitCode}, nil
+}
+
+func (c *fakeExecClient) ContainerExecStart(_ context.Context, execID string, _ types.ExecStartCheck) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, _ := strconv.Atoi(execID)
+	c.started = append(c.started, c.created[id])
+	return nil
+}
+
+// namedContainer is a container that only has a name and an ID
+type namedContainer struct {
+	testcontainers.Container
+}
+
+func (namedContainer) Name(context.Context) (string, error) { return "/app", nil }
+
+func (namedContainer) GetContainerID() string { return "0123456789ab" }
+
+func TestExecStream(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeExecClient{output: append(frame(Stdout, "line 1\nline 2\n"), frame(Stderr, "warning\n")...)}
+
+	var stdout, stderr []string
+	code, err := execStream(ctx, client, namedContainer{}, []string{"migrate", "up"}, ExecOptions{
+		User:     "1000",
+		Env:      map[string]string{"DB": "test"},
+		OnStdout: func(line string) { stdout = append(stdout, line) },
+		OnStderr: func(line string) { stderr = append(stderr, line) },
+	})
+	require.NoError(t, err)
+	require.True(t, code.Success())
+	require.Equal(t, []string{"line 1", "line 2"}, stdout)
+	require.Equal(t, []string{"warning"}, stderr)
+	// the command records its pid, the pid file is removed afterwards
+	require.Len(t, client.created, 2)
+	cmd := client.created[0].Cmd
+	require.Equal(t, []string{shell, "-c", pidWrapper}, cmd[:3])
+	require.Equal(t, []string{"migrate", "up"}, cmd[4:])
+	require.Equal(t, "1000", client.created[0].User)
+	require.Equal(t, []string{"DB=test"}, client.created[0].Env)
+	require.Equal(t, []string{"rm", "-f", cmd[3]}, client.created[1].Cmd)
+
+	// images without sh run the command as is
+	client = &fakeExecClient{noShell: true}
+	_, err = execStream(ctx, client, namedContainer{}, []string{"migrate", "up"}, ExecOptions{})
+	require.NoError(t, err)
+	require.Len(t, client.created, 1)
+	require.Equal(t, []string{"migrate", "up"}, client.created[0].Cmd)
+
+	client.exitCode = 3
+	code, err = execStream(ctx, client, namedContainer{}, []string{"false"}, ExecOptions{})
+	var exitErr *ExitError
+	require.ErrorAs(t, err, &exitErr)
+	require.Equal(t, ExitCode(3), code)
+	require.Equal(t, "/app", exitErr.Container)
+}
+
+func TestExecStreamCancel(t *testing.T) {
+	client := &fakeExecClient{block: true, noShell: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := execStream(ctx, client, namedContainer{}, []string{"sleep", "60"}, ExecOptions{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, client.created, 1)
+
+	client = &fakeExecClient{block: true, output: frame(Stdout, "started\npartial")}
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var lines []string
+	_, err = execStream(ctx, client, namedContainer{}, []string{"sleep", "60"}, ExecOptions{
+		User:     "app",
+		OnStdout: func(line string) { lines = append(lines, line) },
+	})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	// the output is complete once execStream returned, the race detector catches late writes
+	require.Equal(t, []string{"started", "partial"}, lines)
+	require.Len(t, client.created, 2)
+	cmd := client.created[0].Cmd
+	require.Equal(t, []string{shell, "-c", pidWrapper}, cmd[:3])
+	require.Equal(t, []string{"sleep", "60"}, cmd[4:])
+	// the kill reads the pid file of the command as its user
+	kill := client.started[0]
+	require.Equal(t, "app", kill.User)
+	require.Equal(t, cmd[3], kill.Cmd[len(kill.Cmd)-1])
+}
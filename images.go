@@ -0,0 +1,223 @@
+package testcontainers
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// EnvRegistryMirror is the environment variable holding a registry prefix,
+// e.g. mirror.example.com:5000, put in front of every catalog image without a registry
+const EnvRegistryMirror = "TESTCONTAINERS_REGISTRY_MIRROR"
+
+// ImageSpec is the known-good image of a module
+type ImageSpec struct {
+	Repository string
+	// Tag is the pinned default tag
+	Tag string
+	// Digest, e.g. sha256:..., pins the default tag to exact content when set
+	Digest string
+	// Supported are the tags the module works with as space separated
+	// comparisons, e.g. ">=6.0 <8.0". Tags that are not versions are not checked.
+	Supported string
+}
+
+var (
+	catalogMu sync.RWMutex
+	catalog   = map[string]ImageSpec{
+		"mongo": {Repository: "mongo", Tag: "6.0.2", Supported: ">=5.0"}, // mongosh ships since 5.0
+		"redis": {Repository: "redis", Tag: "7.0.5", Supported: ">=5.0"},
+		// zookeeper mode was removed in 8.0
+		"kafka":     {Repository: "confluentinc/cp-kafka", Tag: "7.3.3", Supported: ">=6.0 <8.0"},
+		"zookeeper": {Repository: "bitnami/zookeeper", Tag: "3.8.1", Supported: ">=3.5"},
+		"minio":     {Repository: "minio/minio", Tag: "RELEASE.2022-10-20T00-55-09Z"},
+		"rabbitmq":  {Repository: "rabbitmq", Tag: "3.11.16", Supported: ">=3.8"},
+	}
+)
+
+// RegisterImage adds or replaces the catalog entry of a module
+func RegisterImage(module string, spec ImageSpec) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+	catalog[module] = spec
+}
+
+// CatalogImage returns the catalog entry of a module
+func CatalogImage(module string) (ImageSpec, bool) {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	spec, ok := catalog[module]
+	return spec, ok
+}
+
+// CatalogModules returns the modules in the catalog, sorted
+func CatalogModules() []string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	modules := make([]string, 0, len(catalog))
+	for module := range catalog {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// ImageEnv returns the environment variable overriding the image of a module,
+// e.g. TESTCONTAINERS_MONGO_IMAGE
+func ImageEnv(module string) string {
+	return moduleEnv(module, "IMAGE")
+}
+
+var unsafeEnvChars = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// Image resolves the image a module starts. The tag passed in the module options wins
+// over the image in ImageEnv, which wins over the image in the configuration file
+// and then over the pinned catalog tag.
+// Images without a registry are prefixed with EnvRegistryMirror.
+func Image(module string, tag string) (string, error) {
+	spec, ok := CatalogImage(module)
+	if !ok {
+		return "", fmt.Errorf("failed to resolve image: unknown module %s", module)
+	}
+	config, err := LoadConfig()
+	if err != nil {
+		return "", err
+	}
+
+	repository, ref := spec.Repository, ":"+spec.Tag
+	if spec.Digest != "" {
+		ref += "@" + spec.Digest
+	}
+	switch override := config.Modules[module].Image; {
+	case tag != "":
+		ref = ":" + tag
+	case override != "":
+		repository, ref = splitImage(override)
+	}
+	if err := spec.check(strings.TrimPrefix(ref, ":")); err != nil {
+		return "", fmt.Errorf("failed to resolve image of %s: %v", module, err)
+	}
+
+	if mirror := strings.TrimSuffix(config.RegistryMirror, "/"); mirror != "" && !hasRegistry(repository) {
+		repository = mirror + "/" + repository
+	}
+	return repository + ref, nil
+}
+
+// ReaperImage returns the image of the Ryuk reaper that testcontainers-go starts with the first
+// container of a session, on the registry mirror if one is set. It is false when the reaper is
+// disabled by TESTCONTAINERS_RYUK_DISABLED.
+func ReaperImage() (string, bool, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return "", false, err
+	}
+	if disabled, _ := strconv.ParseBool(os.Getenv("TESTCONTAINERS_RYUK_DISABLED")); disabled {
+		return "", false, nil
+	}
+	image := testcontainers.ReaperDefaultImage
+	if mirror := strings.TrimSuffix(config.RegistryMirror, "/"); mirror != "" {
+		// the default image names docker hub explicitly
+		image = mirror + "/" + strings.TrimPrefix(image, "docker.io/")
+	}
+	return image, true, nil
+}
+
+// withReaperImage makes testcontainers-go start the reaper from the registry mirror
+func withReaperImage(options []testcontainers.ContainerOption) ([]testcontainers.ContainerOption, error) {
+	image, enabled, err := ReaperImage()
+	if err != nil || !enabled || image == testcontainers.ReaperDefaultImage {
+		return options, err
+	}
+	return append(options, testcontainers.WithImageName(image)), nil
+}
+
+// splitImage splits an image into the repository and the :tag and/or @digest suffix
+func splitImage(image string) (repository, ref string) {
+	repository = image
+	if i := strings.Index(repository, "@"); i >= 0 {
+		repository, ref = repository[:i], repository[i:]
+	}
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository, ref = repository[:i], repository[i:]+ref
+	}
+	return repository, ref
+}
+
+// hasRegistry reports whether the first path component of a repository is a registry host
+func hasRegistry(repository string) bool {
+	i := strings.Index(repository, "/")
+	if i < 0 {
+		return false
+	}
+	host := repository[:i]
+	return strings.ContainsAny(host, ".:") || host == "localhost"
+}
+
+var versionPrefix = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)`)
+
+// check reports an error when tag is a version outside of the supported range
+func (spec ImageSpec) check(tag string) error {
+	if i := strings.Index(tag, "@"); i >= 0 {
+		tag = tag[:i]
+	}
+	match := versionPrefix.FindStringSubmatch(tag)
+	if spec.Supported == "" || match == nil {
+		return nil
+	}
+	version := parseVersion(match[1])
+	for _, constraint := range strings.Fields(spec.Supported) {
+		op := constraint[:len(constraint)-len(strings.TrimLeft(constraint, "<>=!"))]
+		bound := parseVersion(strings.TrimPrefix(constraint, op))
+		cmp := compareVersions(version, bound)
+		var ok bool
+		switch op {
+		case ">=":
+			ok = cmp >= 0
+		case ">":
+			ok = cmp > 0
+		case "<=":
+			ok = cmp <= 0
+		case "<":
+			ok = cmp < 0
+		case "=", "==", "":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		default:
+			return fmt.Errorf("invalid supported range %q", spec.Supported)
+		}
+		if !ok {
+			return fmt.Errorf("tag %s is not supported, supported tags are %s", tag, spec.Supported)
+		}
+	}
+	return nil
+}
+
+func parseVersion(s string) []int {
+	var version []int
+	for _, part := range strings.Split(s, ".") {
+		n, _ := strconv.Atoi(part)
+		version = append(version, n)
+	}
+	return version
+}
+
+// compareVersions compares the components both versions have, so 6.0.6 matches =6.0
+func compareVersions(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,109 @@
+package testcontainers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestImage(t *testing.T) {
+	isolateConfig(t)
+
+	image, err := Image("mongo", "")
+	require.NoError(t, err)
+	require.Equal(t, "mongo:6.0.2", image)
+
+	// the module option wins over the environment
+	t.Setenv("TESTCONTAINERS_MONGO_IMAGE", "mongo:7.0")
+	image, err = Image("mongo", "")
+	require.NoError(t, err)
+	require.Equal(t, "mongo:7.0", image)
+	image, err = Image("mongo", "6.0")
+	require.NoError(t, err)
+	require.Equal(t, "mongo:6.0", image)
+
+	_, err = Image("mongo", "4.4")
+	require.ErrorContains(t, err, "tag 4.4 is not supported, supported tags are >=5.0")
+	_, err = Image("kafka", "8.0.0")
+	require.Error(t, err)
+	image, err = Image("kafka", "latest")
+	require.NoError(t, err)
+	require.Equal(t, "confluentinc/cp-kafka:latest", image)
+
+	_, err = Image("cassandra", "")
+	require.Error(t, err)
+}
+
+func TestImageMirror(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv(EnvRegistryMirror, "mirror.example.com:5000/")
+	t.Setenv(ImageEnv("kafka"), "registry.example.com/confluentinc/cp-kafka:7.4.0@sha256:abc")
+
+	image, err := Image("redis", "")
+	require.NoError(t, err)
+	require.Equal(t, "mirror.example.com:5000/redis:7.0.5", image)
+
+	// images with a registry are not mirrored
+	image, err = Image("kafka", "")
+	require.NoError(t, err)
+	require.Equal(t, "registry.example.com/confluentinc/cp-kafka:7.4.0@sha256:abc", image)
+}
+
+func TestReaperImage(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("TESTCONTAINERS_RYUK_DISABLED", "")
+	image, enabled, err := ReaperImage()
+	require.NoError(t, err)
+	require.True(t, enabled)
+	require.Equal(t, testcontainers.ReaperDefaultImage, image)
+	options, err := withReaperImage(nil)
+	require.NoError(t, err)
+	require.Empty(t, options)
+
+	t.Setenv(EnvRegistryMirror, "mirror.example.com:5000/")
+	image, _, err = ReaperImage()
+	require.NoError(t, err)
+	require.Equal(t, "mirror.example.com:5000/testcontainers/ryuk:0.4.0", image)
+	options, err = withReaperImage(nil)
+	require.NoError(t, err)
+	require.Len(t, options, 1)
+
+	t.Setenv("TESTCONTAINERS_RYUK_DISABLED", "true")
+	_, enabled, err = ReaperImage()
+	require.NoError(t, err)
+	require.False(t, enabled)
+}
+
+func TestRegisterImage(t *testing.T) {
+	isolateConfig(t)
+	RegisterImage("postgres", ImageSpec{Repository: "postgres", Tag: "15.3", Digest: "sha256:def", Supported: ">=12 !=13"})
+	defer func() {
+		catalogMu.Lock()
+		delete(catalog, "postgres")
+		catalogMu.Unlock()
+	}()
+	require.Contains(t, CatalogModules(), "postgres")
+
+	image, err := Image("postgres", "")
+	require.NoError(t, err)
+	require.Equal(t, "postgres:15.3@sha256:def", image)
+	_, err = Image("postgres", "13.11-alpine")
+	require.Error(t, err)
+	_, err = Image("postgres", "14-alpine")
+	require.NoError(t, err)
+}
+
+func TestSplitImage(t *testing.T) {
+	for image, want := range map[string][2]string{
+		"redis":                           {"redis", ""},
+		"redis:7":                         {"redis", ":7"},
+		"localhost:5000/redis":            {"localhost:5000/redis", ""},
+		"localhost:5000/redis:7@sha256:1": {"localhost:5000/redis", ":7@sha256:1"},
+	} {
+		repository, ref := splitImage(image)
+		require.Equal(t, want, [2]string{repository, ref}, image)
+	}
+	require.True(t, hasRegistry("localhost/redis"))
+	require.False(t, hasRegistry("confluentinc/cp-kafka"))
+}
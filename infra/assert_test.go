@@ -1,6 +1,7 @@
 package infra
 
 import (
+	"context"
 	"net"
 	"os"
 	"strconv"
@@ -35,12 +36,12 @@ func assertPortIsClosed(t *testing.T, port int) {
 }
 
 func assertContainerExists(t *testing.T, name string) {
-	exists, err := testcontainers.ContainerExists(name)
+	exists, err := testcontainers.ContainerExists(context.Background(), name)
 	require.NoError(t, err)
 	require.True(t, exists)
 }
 
 func assertContainerNotExists(t *testing.T, name string) {
-	exists, _ := testcontainers.ContainerExists(name)
+	exists, _ := testcontainers.ContainerExists(context.Background(), name)
 	require.False(t, exists)
 }
@@ -0,0 +1,161 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	tc "github.com/mmadfox/testcontainers"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/go-redis/redis"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+type ComposeOption func(options *composeOptions)
+
+type composeOptions struct {
+	stack     tc.ComposeOptions
+	mongo     string
+	redis     string
+	kafka     string
+	kafkaPort nat.Port
+}
+
+// ComposeProfiles enables the services of these profiles
+func ComposeProfiles(profiles ...string) ComposeOption {
+	return func(opts *composeOptions) {
+		opts.stack.Profiles = append(opts.stack.Profiles, profiles...)
+	}
+}
+
+// ComposeWaitFor replaces the wait strategy of a service
+func ComposeWaitFor(service string, strategy wait.Strategy) ComposeOption {
+	return func(opts *composeOptions) {
+		if opts.stack.WaitFor == nil {
+			opts.stack.WaitFor = make(map[string]wait.Strategy)
+		}
+		opts.stack.WaitFor[service] = strategy
+	}
+}
+
+// ComposeProject prefixes the names of the containers and the network
+func ComposeProject(project string) ComposeOption {
+	return func(opts *composeOptions) {
+		opts.stack.Project = project
+	}
+}
+
+// ComposeMongo makes MongoDB connect to the port 27017 of a service
+func ComposeMongo(service string) ComposeOption {
+	return func(opts *composeOptions) {
+		opts.mongo = service
+	}
+}
+
+// ComposeRedis makes RedisClient connect to the port 6379 of a service
+func ComposeRedis(service string) ComposeOption {
+	return func(opts *composeOptions) {
+		opts.redis = service
+	}
+}
+
+// ComposeKafka makes KafkaAddr return the published port of a service, e.g. "9092/tcp".
+// The broker must advertise that address, so the port is usually published on a fixed host port.
+func ComposeKafka(service string, port nat.Port) ComposeOption {
+	return func(opts *composeOptions) {
+		opts.kafka = service
+		opts.kafkaPort = port
+	}
+}
+
+// SetupCompose starts a compose file through the docker API, the stack is removed on Close
+func (i *Sets) SetupCompose(ctx context.Context, path string, opts ...ComposeOption) {
+	if i.err != nil {
+		return
+	}
+	i.err = i.setupCompose(i.observe(ctx), path, opts...)
+}
+
+func (i *Sets) setupCompose(ctx context.Context, path string, opts ...ComposeOption) (err error) {
+	composeOpts := &composeOptions{}
+	for _, fn := range opts {
+		fn(composeOpts)
+	}
+
+	stack, err := tc.Compose(ctx, path, composeOpts.stack)
+	if err != nil {
+		return err
+	}
+	i.mu.Lock()
+	i.compose = append(i.compose, stack)
+	i.mu.Unlock()
+	closers := []func() error{func() error {
+		ctx, cancel := teardownContext(ctx)
+		defer cancel()
+		return stack.Down(ctx)
+	}}
+	defer func() {
+		// registered last so that clients disconnect before the containers are removed
+		i.register(func() error {
+			var errs []error
+			for x := len(closers) - 1; x >= 0; x-- {
+				errs = append(errs, closers[x]())
+			}
+			return errors.Join(errs...)
+		})
+	}()
+
+	if composeOpts.mongo != "" {
+		addr, err := stack.Endpoint(ctx, composeOpts.mongo, "27017/tcp")
+		if err != nil {
+			return err
+		}
+		client, err := mongo.NewClient(options.Client().ApplyURI(fmt.Sprintf("mongodb://%s/?directConnection=true", addr)))
+		if err != nil {
+			return err
+		}
+		if err := client.Connect(ctx); err != nil {
+			return err
+		}
+		closers = append(closers, func() error {
+			return client.Disconnect(context.Background())
+		})
+		if err := client.Ping(ctx, readpref.Primary()); err != nil {
+			return err
+		}
+		i.mongo = client.Database("testdatabase")
+	}
+
+	if composeOpts.redis != "" {
+		addr, err := stack.Endpoint(ctx, composeOpts.redis, "6379/tcp")
+		if err != nil {
+			return err
+		}
+		client := redis.NewClient(&redis.Options{Addr: addr, DB: 1})
+		closers = append(closers, client.Close)
+		if err := client.WithContext(ctx).Ping().Err(); err != nil {
+			return fmt.Errorf("failed to ping redis of service %s: %v", composeOpts.redis, err)
+		}
+		i.redis = client
+	}
+
+	if composeOpts.kafka != "" {
+		addr, err := stack.Endpoint(ctx, composeOpts.kafka, composeOpts.kafkaPort)
+		if err != nil {
+			return err
+		}
+		i.kafkaAddr = []string{addr}
+	}
+	return nil
+}
+
+// ComposeStacks returns the stacks started by SetupCompose
+func (i *Sets) ComposeStacks() []*tc.ComposeStack {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return append([]*tc.ComposeStack(nil), i.compose...)
+}
@@ -0,0 +1,29 @@
+package infra
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetsCompose(t *testing.T) {
+	sets := NewSets()
+	ctx := context.Background()
+	defer func() {
+		require.NoError(t, sets.Close())
+	}()
+
+	sets.SetupCompose(ctx, "testdata/docker-compose.yml",
+		ComposeMongo("mongo"),
+		ComposeRedis("redis"),
+	)
+	require.NoError(t, sets.Err())
+	require.NoError(t, sets.RedisClient().Ping().Err())
+	require.NoError(t, sets.MongoDB().Client().Ping(ctx, nil))
+
+	stacks := sets.ComposeStacks()
+	require.Len(t, stacks, 1)
+	// debug is in a profile that is not enabled
+	require.Equal(t, []string{"mongo", "redis"}, stacks[0].Services())
+}
@@ -2,6 +2,8 @@ package infra
 
 import (
 	"context"
+	"errors"
+	"testing"
 
 	tc "github.com/mmadfox/testcontainers"
 
@@ -12,7 +14,7 @@ type KafkaOption func(options *kafkaOptions)
 
 type kafkaOptions struct {
 	container *tckafka.Options
-	logger    bool
+	logSink   tc.LogSink
 }
 
 type KafkaBroker struct {
@@ -20,7 +22,7 @@ type KafkaBroker struct {
 	Version string
 }
 
-func Kafka(ctx context.Context, opts ...KafkaOption) (broker KafkaBroker, terminate func(), err error) {
+func Kafka(ctx context.Context, opts ...KafkaOption) (broker KafkaBroker, terminate func() error, err error) {
 	tcOpts := &kafkaOptions{
 		container: &tckafka.Options{},
 	}
@@ -35,46 +37,48 @@ func Kafka(ctx context.Context, opts ...KafkaOption) (broker KafkaBroker, termin
 	}
 	defer func() {
 		if err != nil {
-			container.Terminate(ctx)
+			ctx, cancel := teardownContext(ctx)
+			defer cancel()
+			_ = container.Terminate(ctx)
 		}
 	}()
 
-	var kafkaLogger, zookeeperLogger tc.LogCollector
+	var kafkaLogger, zookeeperLogger *tc.LogCollector
 
-	if tcOpts.logger {
-		kafkaLogger, err = tc.StartLogger(ctx, container.Kafka.Container)
+	if tcOpts.logSink != nil {
+		kafkaLogger, err = tc.StartLogger(context.WithoutCancel(ctx), container.Kafka.Container, tc.WithLogSink(tcOpts.logSink))
 		if err != nil {
 			return broker, nil, err
-		} else {
-			go kafkaLogger.LogToStdout()
 		}
 
-		zookeeperLogger, err = tc.StartLogger(ctx, container.Zookeeper.Container)
+		zookeeperLogger, err = tc.StartLogger(context.WithoutCancel(ctx), container.Zookeeper.Container, tc.WithLogSink(tcOpts.logSink))
 		if err != nil {
+			_ = kafkaLogger.Stop()
 			return broker, nil, err
-		} else {
-			go zookeeperLogger.LogToStdout()
 		}
 	}
 
 	broker.Addr = container.Kafka.Brokers
 	broker.Version = container.Kafka.Version
 
-	return broker, func() {
-		container.Terminate(ctx)
-
-		if kafkaLogger.LogChan != nil {
-			kafkaLogger.Stop()
+	return broker, func() error {
+		ctx, cancel := teardownContext(ctx)
+		defer cancel()
+		errs := []error{container.Terminate(ctx)}
+		if kafkaLogger != nil {
+			errs = append(errs, kafkaLogger.Stop())
 		}
-		if zookeeperLogger.LogChan != nil {
-			zookeeperLogger.Stop()
+		if zookeeperLogger != nil {
+			errs = append(errs, zookeeperLogger.Stop())
 		}
+		return errors.Join(errs...)
 	}, nil
 }
 
-func KafkaEnableLogger() KafkaOption {
+// KafkaEnableLogger sends the kafka and zookeeper output to sink, nil means tc.StdoutSink
+func KafkaEnableLogger(sink tc.LogSink) KafkaOption {
 	return func(opts *kafkaOptions) {
-		opts.logger = true
+		opts.logSink = defaultLogSink(sink)
 	}
 }
 
@@ -90,6 +94,13 @@ func ZookeeperContainerName(name string) KafkaOption {
 	}
 }
 
+// KafkaTest names the containers after the test and stamps them with its name, see testcontainers.ResourceName
+func KafkaTest(tb testing.TB) KafkaOption {
+	return func(opts *kafkaOptions) {
+		opts.container.TB = tb
+	}
+}
+
 func KafkaImageTag(tag string) KafkaOption {
 	return func(opts *kafkaOptions) {
 		opts.container.KafkaImageTag = tag
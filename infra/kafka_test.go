@@ -14,10 +14,11 @@ func TestKafka(t *testing.T) {
 	kafkaContainerName := "infra-01-kafka-container"
 	zookeeperContainerName := "infra-01-zookeeper-container"
 
-	testcontainers.DropContainers([]string{
+	err := testcontainers.DropContainers(ctx, []string{
 		kafkaContainerName,
 		zookeeperContainerName,
 	})
+	require.NoError(t, err)
 
 	broker, terminate, err := Kafka(ctx,
 		KafkaContainerName(kafkaContainerName),
@@ -28,21 +29,21 @@ func TestKafka(t *testing.T) {
 	require.NotEmpty(t, broker.Addr)
 	require.NotEmpty(t, broker.Version)
 
-	kafkaContainerExists, err := testcontainers.ContainerExists(kafkaContainerName)
+	kafkaContainerExists, err := testcontainers.ContainerExists(ctx, kafkaContainerName)
 	require.NoError(t, err)
 	require.True(t, kafkaContainerExists)
 
-	zooContainerExists, err := testcontainers.ContainerExists(zookeeperContainerName)
+	zooContainerExists, err := testcontainers.ContainerExists(ctx, zookeeperContainerName)
 	require.NoError(t, err)
 	require.True(t, zooContainerExists)
 
-	terminate()
+	require.NoError(t, terminate())
 
-	kafkaContainerExists, err = testcontainers.ContainerExists(kafkaContainerName)
+	kafkaContainerExists, err = testcontainers.ContainerExists(ctx, kafkaContainerName)
 	require.NoError(t, err)
 	require.False(t, kafkaContainerExists)
 
-	zooContainerExists, err = testcontainers.ContainerExists(zookeeperContainerName)
+	zooContainerExists, err = testcontainers.ContainerExists(ctx, zookeeperContainerName)
 	require.NoError(t, err)
 	require.False(t, zooContainerExists)
 }
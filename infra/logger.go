@@ -0,0 +1,12 @@
+package infra
+
+import (
+	tc "github.com/mmadfox/testcontainers"
+)
+
+func defaultLogSink(sink tc.LogSink) tc.LogSink {
+	if sink == nil {
+		return tc.StdoutSink()
+	}
+	return sink
+}
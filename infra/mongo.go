@@ -2,6 +2,8 @@ package infra
 
 import (
 	"context"
+	"errors"
+	"testing"
 	"time"
 
 	tc "github.com/mmadfox/testcontainers"
@@ -15,11 +17,31 @@ type MongoOption func(options *mongoOptions)
 
 type mongoOptions struct {
 	container  *tcmongo.Options
-	logger     bool
+	logSink    tc.LogSink
 	replicaSet bool
 }
 
-func Mongo(ctx context.Context, opts ...MongoOption) (db *mongo.Database, terminate func(), err error) {
+// MongoInstance is a started mongo container, or replica set, with its database
+type MongoInstance struct {
+	DB *mongo.Database
+	// Port is the host port the (master) container is bound to,
+	// picked by docker unless MongoContainerPort is set
+	Port int
+	// ContainerNames are the names of the replica set members, empty for a standalone container
+	ContainerNames []string
+	Terminate      func() error
+}
+
+func Mongo(ctx context.Context, opts ...MongoOption) (db *mongo.Database, terminate func() error, err error) {
+	m, err := StartMongo(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m.DB, m.Terminate, nil
+}
+
+// StartMongo is Mongo returning the instance, e.g. to read the bound port
+func StartMongo(ctx context.Context, opts ...MongoOption) (MongoInstance, error) {
 	tcOpts := &mongoOptions{
 		container: &tcmongo.Options{},
 	}
@@ -35,84 +57,93 @@ func Mongo(ctx context.Context, opts ...MongoOption) (db *mongo.Database, termin
 	}
 }
 
-func replicaSetMongo(ctx context.Context, opts *mongoOptions) (db *mongo.Database, terminate func(), err error) {
+func replicaSetMongo(ctx context.Context, opts *mongoOptions) (m MongoInstance, err error) {
 	container, err := tcmongo.StartReplicaSet(ctx, *opts.container)
 	if err != nil {
-		return nil, nil, err
+		return m, err
 	}
 	defer func() {
 		if err != nil {
-			container.Terminate(ctx)
-			tc.DropContainers(container.ContainerNames)
+			ctx, cancel := teardownContext(ctx)
+			defer cancel()
+			_ = container.Terminate(ctx)
+			_ = tc.DropContainers(ctx, container.ContainerNames)
 		}
 	}()
 
 	mongoURI := container.MasterConnectionURI()
 	client, err := mongo.NewClient(options.Client().ApplyURI(mongoURI))
 	if err != nil {
-		return nil, nil, err
+		return m, err
 	}
 	if err = client.Connect(ctx); err != nil {
-		return nil, nil, err
+		return m, err
 	}
 
 	err = client.Ping(ctx, readpref.Primary())
 	if err != nil {
-		return nil, nil, err
+		return m, err
 	}
 	database := client.Database("testdatabase")
 
-	return database, func() {
-		_ = client.Disconnect(ctx)
-		container.Terminate(ctx)
-		tc.DropContainers(container.ContainerNames)
-	}, nil
+	return MongoInstance{DB: database, Port: int(container.MasterContainerAddr.Port), ContainerNames: container.ContainerNames, Terminate: func() error {
+		ctx, cancel := teardownContext(ctx)
+		defer cancel()
+		return errors.Join(
+			client.Disconnect(ctx),
+			container.Terminate(ctx),
+			tc.DropContainers(ctx, container.ContainerNames),
+		)
+	}}, nil
 }
 
-func standaloneMongo(ctx context.Context, opts *mongoOptions) (db *mongo.Database, terminate func(), err error) {
+func standaloneMongo(ctx context.Context, opts *mongoOptions) (m MongoInstance, err error) {
 	container, err := tcmongo.Start(ctx, *opts.container)
 	if err != nil {
-		return nil, nil, err
+		return m, err
 	}
 	defer func() {
 		if err != nil {
-			container.Terminate(ctx)
+			ctx, cancel := teardownContext(ctx)
+			defer cancel()
+			_ = container.Terminate(ctx)
 		}
 	}()
 
-	var logger tc.LogCollector
+	var logger *tc.LogCollector
 
-	if opts.logger {
-		logger, err = tc.StartLogger(ctx, container.Container)
+	if opts.logSink != nil {
+		logger, err = tc.StartLogger(context.WithoutCancel(ctx), container.Container, tc.WithLogSink(opts.logSink))
 		if err != nil {
-			return nil, nil, err
-		} else {
-			go logger.LogToStdout()
+			return m, err
 		}
 	}
 
 	mongoURI := container.ConnectionURI()
 	client, err := mongo.NewClient(options.Client().ApplyURI(mongoURI))
 	if err != nil {
-		return nil, nil, err
+		return m, err
 	}
 	if err = client.Connect(ctx); err != nil {
-		return nil, nil, err
+		return m, err
 	}
 
 	err = client.Ping(ctx, readpref.Primary())
 	if err != nil {
-		return nil, nil, err
+		return m, err
 	}
 	database := client.Database("testdatabase")
 
-	return database, func() {
-		_ = client.Disconnect(ctx)
-		if logger.LogChan != nil {
-			logger.Stop()
+	return MongoInstance{DB: database, Port: int(container.Port), Terminate: func() error {
+		ctx, cancel := teardownContext(ctx)
+		defer cancel()
+		errs := []error{client.Disconnect(ctx)}
+		if logger != nil {
+			errs = append(errs, logger.Stop())
 		}
-		container.Terminate(ctx)
-	}, nil
+		errs = append(errs, container.Terminate(ctx))
+		return errors.Join(errs...)
+	}}, nil
 }
 
 func MongoEnableReplicaSet() MongoOption {
@@ -121,9 +152,10 @@ func MongoEnableReplicaSet() MongoOption {
 	}
 }
 
-func MongoEnableLogger() MongoOption {
+// MongoEnableLogger sends the container output to sink, nil means tc.StdoutSink
+func MongoEnableLogger(sink tc.LogSink) MongoOption {
 	return func(opts *mongoOptions) {
-		opts.logger = true
+		opts.logSink = defaultLogSink(sink)
 	}
 }
 
@@ -139,6 +171,14 @@ func MongoContainerName(name string) MongoOption {
 	}
 }
 
+// MongoTest names the containers after the test and stamps them with its name, see testcontainers.ResourceName
+func MongoTest(tb testing.TB) MongoOption {
+	return func(opts *mongoOptions) {
+		opts.container.TB = tb
+	}
+}
+
+// MongoContainerPort pins the host port, without it docker picks a free one
 func MongoContainerPort(port int) MongoOption {
 	return func(opts *mongoOptions) {
 		opts.container.Port = port
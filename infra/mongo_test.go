@@ -21,7 +21,7 @@ func TestMongo(t *testing.T) {
 	testVal := "test"
 	containerName := "infra-01-mongo-container"
 
-	testcontainers.DropContainerIfExists(containerName)
+	require.NoError(t, testcontainers.DropContainerIfExists(ctx, containerName))
 
 	db, terminate, err := Mongo(ctx,
 		MongoContainerPort(mongoPort),
@@ -48,7 +48,7 @@ func TestMongo(t *testing.T) {
 	require.NoError(t, doc.Decode(&val))
 	require.Equal(t, testVal, val.Value)
 
-	terminate()
+	require.NoError(t, terminate())
 
 	assertPortIsClosed(t, mongoPort)
 	assertContainerNotExists(t, containerName)
@@ -95,7 +95,7 @@ func TestMongoMultiContainers(t *testing.T) {
 	}
 
 	ctx := context.Background()
-	terminates := make([]func(), 0)
+	terminates := make([]func() error, 0)
 	for _, tc := range testCases {
 		db, terminate, err := Mongo(ctx, MongoContainerPort(tc.port), MongoContainerName(tc.name))
 		require.NoError(t, err)
@@ -109,7 +109,7 @@ func TestMongoMultiContainers(t *testing.T) {
 	}
 
 	for _, terminate := range terminates {
-		terminate()
+		require.NoError(t, terminate())
 	}
 
 	for _, tc := range testCases {
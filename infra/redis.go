@@ -2,6 +2,8 @@ package infra
 
 import (
 	"context"
+	"errors"
+	"testing"
 	"time"
 
 	"github.com/go-redis/redis"
@@ -14,10 +16,27 @@ type RedisOption func(*redisOptions)
 type redisOptions struct {
 	container *tcredis.Options
 	server    *redis.Options
-	logger    bool
+	logSink   testcontainers.LogSink
 }
 
-func Redis(ctx context.Context, opts ...RedisOption) (cli *redis.Client, terminate func(), err error) {
+// RedisInstance is a started redis container with its client
+type RedisInstance struct {
+	Client *redis.Client
+	// Port is the host port the container is bound to, picked by docker unless RedisContainerPort is set
+	Port      int
+	Terminate func() error
+}
+
+func Redis(ctx context.Context, opts ...RedisOption) (cli *redis.Client, terminate func() error, err error) {
+	r, err := StartRedis(ctx, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.Client, r.Terminate, nil
+}
+
+// StartRedis is Redis returning the instance, e.g. to read the bound port
+func StartRedis(ctx context.Context, opts ...RedisOption) (r RedisInstance, err error) {
 	tcOpts := &redisOptions{
 		container: &tcredis.Options{},
 		server: &redis.Options{
@@ -31,40 +50,44 @@ func Redis(ctx context.Context, opts ...RedisOption) (cli *redis.Client, termina
 
 	container, err := tcredis.Start(ctx, *tcOpts.container)
 	if err != nil {
-		return nil, nil, err
+		return r, err
 	}
 	defer func() {
 		if err != nil {
-			container.Terminate(ctx)
+			ctx, cancel := teardownContext(ctx)
+			defer cancel()
+			_ = container.Terminate(ctx)
 		}
 	}()
 
-	var logger testcontainers.LogCollector
+	var logger *testcontainers.LogCollector
 
-	if tcOpts.logger {
-		logger, err = testcontainers.StartLogger(ctx, container.Container)
+	if tcOpts.logSink != nil {
+		logger, err = testcontainers.StartLogger(context.WithoutCancel(ctx), container.Container, testcontainers.WithLogSink(tcOpts.logSink))
 		if err != nil {
-			return nil, nil, err
-		} else {
-			go logger.LogToStdout()
+			return r, err
 		}
 	}
 
 	tcOpts.server.Addr = container.ConnectionURI()
 	db := redis.NewClient(tcOpts.server)
 
-	return db, func() {
-		_ = db.Close()
-		if logger.LogChan != nil {
-			logger.Stop()
+	return RedisInstance{Client: db, Port: int(container.Port), Terminate: func() error {
+		ctx, cancel := teardownContext(ctx)
+		defer cancel()
+		errs := []error{db.Close()}
+		if logger != nil {
+			errs = append(errs, logger.Stop())
 		}
-		container.Terminate(ctx)
-	}, nil
+		errs = append(errs, container.Terminate(ctx))
+		return errors.Join(errs...)
+	}}, nil
 }
 
-func RedisEnableLogger() RedisOption {
+// RedisEnableLogger sends the container output to sink, nil means testcontainers.StdoutSink
+func RedisEnableLogger(sink testcontainers.LogSink) RedisOption {
 	return func(opts *redisOptions) {
-		opts.logger = true
+		opts.logSink = defaultLogSink(sink)
 	}
 }
 
@@ -86,6 +109,14 @@ func RedisContainerName(name string) RedisOption {
 	}
 }
 
+// RedisTest names the containers after the test and stamps them with its name, see testcontainers.ResourceName
+func RedisTest(tb testing.TB) RedisOption {
+	return func(opts *redisOptions) {
+		opts.container.TB = tb
+	}
+}
+
+// RedisContainerPort pins the host port, without it docker picks a free one
 func RedisContainerPort(port int) RedisOption {
 	return func(opts *redisOptions) {
 		opts.container.Port = port
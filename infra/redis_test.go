@@ -15,7 +15,7 @@ func TestRedis(t *testing.T) {
 	testVal := "test"
 	containerName := "infra-01-redis-container"
 
-	testcontainers.DropContainerIfExists(containerName)
+	require.NoError(t, testcontainers.DropContainerIfExists(ctx, containerName))
 
 	db, terminate, err := Redis(ctx,
 		RedisContainerPort(redisPort),
@@ -33,8 +33,19 @@ func TestRedis(t *testing.T) {
 	require.NotNil(t, cmd)
 	require.EqualValues(t, testVal, cmd.Val())
 
-	terminate()
+	require.NoError(t, terminate())
 
 	assertPortIsClosed(t, redisPort)
 	assertContainerNotExists(t, containerName)
 }
+
+func TestStartRedisReportsPort(t *testing.T) {
+	r, err := StartRedis(context.Background(), RedisTest(t))
+	require.NoError(t, err)
+	require.NotZero(t, r.Port)
+	assertPortIsOpened(t, r.Port)
+	require.NoError(t, r.Client.Ping().Err())
+
+	require.NoError(t, r.Terminate())
+	assertPortIsClosed(t, r.Port)
+}
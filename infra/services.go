@@ -0,0 +1,194 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	tc "github.com/mmadfox/testcontainers"
+)
+
+// Built-in services of Require
+const (
+	ServiceNetwork         = "network"
+	ServiceMongo           = "mongo"
+	ServiceMongoReplicaSet = "mongo-replica-set"
+	ServiceRedis           = "redis"
+	ServiceKafka           = "kafka"
+)
+
+type service struct {
+	name      string
+	dependsOn []string
+	setup     func(ctx context.Context) error
+	// module is the image catalog entry checked by the preflight, if any
+	module string
+}
+
+// Require declares built-in services for Start. Declared services depend on
+// the network when it is declared too, so they are attached to it.
+func (i *Sets) Require(services ...string) {
+	for _, name := range services {
+		switch name {
+		case ServiceNetwork:
+			i.Add(name, i.setupBridgeNetwork)
+		case ServiceMongo:
+			i.addBuiltin(name, "mongo", i.setupMongo)
+		case ServiceMongoReplicaSet:
+			i.addBuiltin(name, "mongo", i.setupMongoReplicaSet)
+		case ServiceRedis:
+			i.addBuiltin(name, "redis", i.setupRedis)
+		case ServiceKafka:
+			i.addBuiltin(name, "kafka", i.setupKafka)
+		default:
+			i.services = append(i.services, service{name: name, setup: func(context.Context) error {
+				return fmt.Errorf("unknown service %s", name)
+			}})
+		}
+	}
+}
+
+// Add declares a service started by Start once the services it depends on are up
+func (i *Sets) Add(name string, setup func(ctx context.Context) error, dependsOn ...string) {
+	i.services = append(i.services, service{name: name, dependsOn: dependsOn, setup: setup})
+}
+
+// AddModule declares a module started by Start and terminated on Close
+func (i *Sets) AddModule(name string, module tc.Module, dependsOn ...string) {
+	i.Add(name, func(ctx context.Context) error {
+		return i.setupModule(ctx, module)
+	}, dependsOn...)
+}
+
+func (i *Sets) addBuiltin(name, module string, setup func(ctx context.Context) error) {
+	i.services = append(i.services, service{name: name, setup: setup, module: module})
+}
+
+// Start starts the declared services, each one as soon as its dependencies are up,
+// and waits for all of them within timeout, if positive. The timeout only applies to the start,
+// Close removes the services afterwards. A service whose dependency failed is not started
+// and reported as skipped. Every failure is returned joined and kept in Err.
+func (i *Sets) Start(ctx context.Context, timeout time.Duration) error {
+	if i.err != nil {
+		return i.err
+	}
+	services := i.services
+	i.services = nil
+
+	graph, err := serviceGraph(services)
+	if err != nil {
+		i.err = err
+		return err
+	}
+	var modules []string
+	for _, s := range services {
+		if s.module != "" {
+			modules = append(modules, s.module)
+		}
+	}
+	if i.Preflight(ctx, modules...); i.err != nil {
+		return i.err
+	}
+
+	ctx = i.observe(ctx)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	type result struct {
+		done chan struct{}
+		err  error
+	}
+	results := make(map[string]*result, len(services))
+	for _, s := range services {
+		results[s.name] = &result{done: make(chan struct{})}
+	}
+	for _, s := range services {
+		go func(s service, r *result) {
+			defer close(r.done)
+			for _, dep := range graph[s.name] {
+				// the err of a dependency is set before its done is closed
+				<-results[dep].done
+				if results[dep].err != nil {
+					r.err = fmt.Errorf("%s skipped: dependency %s failed", s.name, dep)
+					return
+				}
+			}
+			if err := s.setup(ctx); err != nil {
+				r.err = fmt.Errorf("failed to start %s: %w", s.name, err)
+			}
+		}(s, results[s.name])
+	}
+
+	var failures []error
+	for _, s := range services {
+		<-results[s.name].done
+		failures = append(failures, results[s.name].err)
+	}
+	i.err = errors.Join(failures...)
+	return i.err
+}
+
+// serviceGraph returns the dependencies of every service and rejects unknown and cyclic ones
+func serviceGraph(services []service) (map[string][]string, error) {
+	graph := make(map[string][]string, len(services))
+	network := false
+	for _, s := range services {
+		if _, ok := graph[s.name]; ok {
+			return nil, fmt.Errorf("service %s is declared twice", s.name)
+		}
+		graph[s.name] = append([]string(nil), s.dependsOn...)
+		if s.name == ServiceNetwork {
+			network = true
+		}
+	}
+	for _, s := range services {
+		if s.module != "" && network {
+			graph[s.name] = append(graph[s.name], ServiceNetwork)
+		}
+		for _, dep := range graph[s.name] {
+			if _, ok := graph[dep]; !ok {
+				return nil, fmt.Errorf("service %s depends on undeclared service %s", s.name, dep)
+			}
+		}
+	}
+
+	// depth first search for cycles
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int, len(graph))
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("service dependency cycle: %v", append(path, name))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range graph[name] {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	names := make([]string, 0, len(graph))
+	for name := range graph {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return graph, nil
+}
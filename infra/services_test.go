@@ -0,0 +1,127 @@
+package infra
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tc "github.com/mmadfox/testcontainers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetsStartParallel(t *testing.T) {
+	sets := NewSets()
+	var mu sync.Mutex
+	var order []string
+	started := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		order = append(order, name)
+	}
+
+	// a and b only return once both are running
+	var running sync.WaitGroup
+	running.Add(2)
+	parallel := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			started(name)
+			running.Done()
+			running.Wait()
+			return nil
+		}
+	}
+	sets.Add("network", func(context.Context) error {
+		started("network")
+		return nil
+	})
+	sets.Add("a", parallel("a"), "network")
+	sets.Add("b", parallel("b"), "network")
+	sets.Add("c", func(context.Context) error {
+		started("c")
+		return nil
+	}, "a", "b")
+
+	require.NoError(t, sets.Start(context.Background(), time.Second))
+	require.Equal(t, "network", order[0])
+	require.ElementsMatch(t, []string{"a", "b"}, order[1:3])
+	require.Equal(t, "c", order[3])
+}
+
+func TestSetsStartJoinsErrors(t *testing.T) {
+	sets := NewSets()
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+	dependentStarted := false
+	sets.Add("a", func(context.Context) error { return errA })
+	sets.Add("b", func(ctx context.Context) error {
+		<-ctx.Done()
+		return errB
+	})
+	sets.Add("c", func(context.Context) error {
+		dependentStarted = true
+		return nil
+	}, "a")
+
+	err := sets.Start(context.Background(), 50*time.Millisecond)
+	require.ErrorIs(t, err, errA)
+	require.ErrorIs(t, err, errB)
+	require.ErrorContains(t, err, "failed to start a")
+	require.False(t, dependentStarted)
+	require.Equal(t, err, sets.Err())
+}
+
+func TestSetsStartRejectsBadGraphs(t *testing.T) {
+	setup := func(context.Context) error { return nil }
+
+	sets := NewSets()
+	sets.Add("a", setup, "b")
+	sets.Add("b", setup, "a")
+	require.ErrorContains(t, sets.Start(context.Background(), time.Second), "service dependency cycle")
+
+	sets = NewSets()
+	sets.Add("a", setup, "missing")
+	require.ErrorContains(t, sets.Start(context.Background(), time.Second), "undeclared service missing")
+
+	sets = NewSets()
+	sets.Require("postgres")
+	require.ErrorContains(t, sets.Start(context.Background(), time.Second), "unknown service postgres")
+}
+
+// contextModule fails to terminate with a cancelled context, like the docker client does
+type contextModule struct {
+	tc.Module
+	terminated bool
+}
+
+func (m *contextModule) Start(ctx context.Context) error { return nil }
+
+func (m *contextModule) Terminate(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.terminated = true
+	return nil
+}
+
+func TestSetsCloseAfterStartTimeout(t *testing.T) {
+	sets := NewSets()
+	module := &contextModule{}
+	sets.AddModule("module", module)
+
+	require.NoError(t, sets.Start(context.Background(), time.Second))
+	require.NoError(t, sets.Close())
+	require.True(t, module.terminated)
+}
+
+func TestSetsStartReportsSkipped(t *testing.T) {
+	sets := NewSets()
+	sets.Add("a", func(context.Context) error { return errors.New("a failed") })
+	sets.Add("b", func(context.Context) error { return nil }, "a")
+	sets.Add("c", func(context.Context) error { return nil }, "b")
+
+	err := sets.Start(context.Background(), time.Second)
+	require.ErrorContains(t, err, "b skipped: dependency a failed")
+	require.ErrorContains(t, err, "c skipped: dependency b failed")
+}
@@ -2,6 +2,12 @@ package infra
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
 
 	tc "github.com/mmadfox/testcontainers"
 
@@ -18,6 +24,15 @@ const (
 	DefaultNetwork   = "test-network"
 )
 
+// TeardownTimeout bounds the removal of the containers of a service on Close
+var TeardownTimeout = time.Minute
+
+// teardownContext detaches ctx from the cancellation of the start, e.g. the timeout of Start,
+// which usually has happened by the time the containers are removed
+func teardownContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), TeardownTimeout)
+}
+
 type ContainerNames struct {
 	Mongo     string
 	Redis     string
@@ -31,12 +46,23 @@ type Sets struct {
 
 	redis          *redis.Client
 	mongo          *mongo.Database
+	redisPort      int
+	mongoPort      int
 	kafkaAddr      []string
 	kafkaVersion   string
 	network        testcontainers.Network
 	networkName    string
-	terminates     []func()
+	terminates     []func() error
+	modules        []tc.Module
+	compose        []*tc.ComposeStack
 	containerNames []string
+	preflighted    map[string]bool
+	services       []service
+	report         tc.StartupReport
+	reportTo       io.Writer
+	startupHooks   []tc.StartupHook
+	tb             testing.TB
+	mu             sync.Mutex
 	err            error
 }
 
@@ -53,6 +79,21 @@ func NewSets() *Sets {
 	return sets
 }
 
+// NewTestSets names the containers and the network after tb, see tc.ResourceName,
+// and stamps the containers with the name of the test
+func NewTestSets(tb testing.TB) *Sets {
+	return &Sets{
+		ContainerNames: ContainerNames{
+			Mongo:     tc.ResourceName(tb, DefaultMongo),
+			Redis:     tc.ResourceName(tb, DefaultRedis),
+			Kafka:     tc.ResourceName(tb, DefaultKafka),
+			Zookeeper: tc.ResourceName(tb, DefaultZookeeper),
+			Network:   tc.ResourceName(tb, DefaultNetwork),
+		},
+		tb: tb,
+	}
+}
+
 func (i *Sets) Err() error {
 	return i.err
 }
@@ -65,14 +106,62 @@ func (i *Sets) MongoDB() *mongo.Database {
 	return i.mongo
 }
 
-func (i *Sets) Close() {
+// Close terminates everything started by the sets and returns the joined errors,
+// a non-nil error usually means that containers or networks leaked
+func (i *Sets) Close() error {
+	var errs []error
 	for x := 0; x < len(i.terminates); x++ {
-		i.terminates[x]()
+		errs = append(errs, i.terminates[x]())
+	}
+	if len(i.containerNames) > 0 {
+		errs = append(errs, tc.DropContainers(context.Background(), i.containerNames))
 	}
-	tc.DropContainers(i.containerNames)
 	if i.network != nil {
-		_ = i.network.Remove(context.Background())
+		if err := i.network.Remove(context.Background()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove network %s: %w", i.networkName, err))
+		}
 	}
+	if i.reportTo != nil {
+		if _, err := i.report.WriteTo(i.reportTo); err != nil {
+			errs = append(errs, fmt.Errorf("failed to write startup report: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ReportStartup makes Close write the timing of every phase of the started services to w
+func (i *Sets) ReportStartup(w io.Writer) {
+	i.reportTo = w
+}
+
+// StartupReport returns the timing of the services started so far
+func (i *Sets) StartupReport() *tc.StartupReport {
+	return &i.report
+}
+
+// AddStartupHook observes the phases of the services started by the sets,
+// e.g. with tc.TraceHook to export them as spans
+func (i *Sets) AddStartupHook(hook tc.StartupHook) {
+	i.startupHooks = append(i.startupHooks, hook)
+}
+
+// observe returns a context passing the phases of module starts to the report and hooks
+func (i *Sets) observe(ctx context.Context) context.Context {
+	ctx = tc.WithStartupHook(ctx, i.report.Hook)
+	for _, hook := range i.startupHooks {
+		ctx = tc.WithStartupHook(ctx, hook)
+	}
+	return ctx
+}
+
+// RedisPort returns the host port redis is bound to
+func (i *Sets) RedisPort() int {
+	return i.redisPort
+}
+
+// MongoPort returns the host port mongo (the master of a replica set) is bound to
+func (i *Sets) MongoPort() int {
+	return i.mongoPort
 }
 
 func (i *Sets) KafkaAddr() []string {
@@ -83,15 +172,54 @@ func (i *Sets) KafkaVersion() string {
 	return i.kafkaVersion
 }
 
-func (i *Sets) SetupBridgeNetwork(ctx context.Context) {
+// Preflight checks in offline mode that the images of the services, e.g. "redis"
+// or "kafka", exist locally and reports all missing images at once.
+// Every Setup runs it for its own service, call it first to check all of them
+// before anything starts.
+func (i *Sets) Preflight(ctx context.Context, services ...string) {
 	if i.err != nil {
 		return
 	}
 
-	tc.PruneNetwork()
+	var modules []string
+	for _, service := range services {
+		required := []string{service}
+		if service == "kafka" {
+			// kafka runs with a zookeeper container
+			required = append(required, "zookeeper")
+		}
+		for _, module := range required {
+			if !i.preflighted[module] {
+				modules = append(modules, module)
+			}
+		}
+	}
+	if len(modules) == 0 {
+		return
+	}
+	if err := tc.PreflightModules(ctx, modules...); err != nil {
+		i.err = err
+		return
+	}
+	if i.preflighted == nil {
+		i.preflighted = make(map[string]bool)
+	}
+	for _, module := range modules {
+		i.preflighted[module] = true
+	}
+}
 
+func (i *Sets) SetupBridgeNetwork(ctx context.Context) {
+	if i.err != nil {
+		return
+	}
+	i.err = i.setupBridgeNetwork(ctx)
+}
+
+func (i *Sets) setupBridgeNetwork(ctx context.Context) (err error) {
 	i.networkName = i.ContainerNames.Network
-	i.network, i.err = BridgeNetwork(ctx, i.networkName)
+	i.network, err = BridgeNetwork(ctx, i.networkName)
+	return err
 }
 
 func (i *Sets) RemoveNetwork(ctx context.Context) error {
@@ -102,99 +230,156 @@ func (i *Sets) RemoveNetwork(ctx context.Context) error {
 }
 
 func (i *Sets) SetupRedis(ctx context.Context) {
+	i.Preflight(ctx, "redis")
 	if i.err != nil {
 		return
 	}
+	i.err = i.setupRedis(i.observe(ctx))
+}
+
+func (i *Sets) setupRedis(ctx context.Context) error {
 
 	opts := []RedisOption{
 		RedisContainerName(i.ContainerNames.Redis),
-		RedisContainerPort(3890),
+		RedisTest(i.tb),
 	}
 	if len(i.networkName) > 0 {
 		opts = append(opts, RedisContainerNetwork([]string{i.networkName}))
 	}
-	conn, terminate, err := Redis(ctx, opts...)
+	r, err := StartRedis(ctx, opts...)
 	if err != nil {
-		i.err = err
-		return
+		return err
 	}
 
-	i.redis = conn
-	i.register(terminate, i.ContainerNames.Redis)
+	i.redis = r.Client
+	i.redisPort = r.Port
+	i.register(r.Terminate, i.ContainerNames.Redis)
+	return nil
 }
 
 func (i *Sets) SetupMongo(ctx context.Context) {
+	i.Preflight(ctx, "mongo")
 	if i.err != nil {
 		return
 	}
+	i.err = i.setupMongo(i.observe(ctx))
+}
+
+func (i *Sets) setupMongo(ctx context.Context) error {
 
 	opts := []MongoOption{
 		MongoContainerName(i.ContainerNames.Mongo),
-		MongoContainerPort(2189),
+		MongoTest(i.tb),
 	}
 	if len(i.networkName) > 0 {
 		opts = append(opts, MongoContainerNetwork([]string{i.networkName}))
 	}
-	db, terminate, err := Mongo(ctx, opts...)
+	m, err := StartMongo(ctx, opts...)
 	if err != nil {
-		i.err = err
-		return
+		return err
 	}
 
-	i.mongo = db
-	i.register(terminate, i.ContainerNames.Mongo)
+	i.mongo = m.DB
+	i.mongoPort = m.Port
+	i.register(m.Terminate, i.ContainerNames.Mongo)
+	return nil
 }
 
 func (i *Sets) SetupMongoReplicaSet(ctx context.Context) {
+	i.Preflight(ctx, "mongo")
 	if i.err != nil {
 		return
 	}
+	i.err = i.setupMongoReplicaSet(i.observe(ctx))
+}
+
+func (i *Sets) setupMongoReplicaSet(ctx context.Context) error {
 
 	opts := []MongoOption{
 		MongoContainerName(i.ContainerNames.Mongo),
 		MongoEnableReplicaSet(),
+		MongoTest(i.tb),
 	}
 	if len(i.networkName) > 0 {
 		opts = append(opts, MongoContainerNetwork([]string{i.networkName}))
 	}
-	db, terminate, err := Mongo(ctx, opts...)
+	m, err := StartMongo(ctx, opts...)
 	if err != nil {
-		i.err = err
-		return
+		return err
 	}
 
-	i.mongo = db
-	i.register(terminate,
-		i.ContainerNames.Mongo+"-m1",
-		i.ContainerNames.Mongo+"-rs2",
-		i.ContainerNames.Mongo+"-rs3",
-	)
+	i.mongo = m.DB
+	i.mongoPort = m.Port
+	i.register(m.Terminate, m.ContainerNames...)
+	return nil
 }
 
 func (i *Sets) SetupKafka(ctx context.Context) {
+	i.Preflight(ctx, "kafka")
 	if i.err != nil {
 		return
 	}
+	i.err = i.setupKafka(i.observe(ctx))
+}
+
+func (i *Sets) setupKafka(ctx context.Context) error {
 
 	opts := []KafkaOption{
 		KafkaContainerName(i.ContainerNames.Kafka),
 		ZookeeperContainerName(i.ContainerNames.Zookeeper),
+		KafkaTest(i.tb),
 	}
 	if len(i.networkName) > 0 {
 		opts = append(opts, KafkaContainerNetwork([]string{i.networkName}))
 	}
 	broker, terminate, err := Kafka(ctx, opts...)
 	if err != nil {
-		i.err = err
-		return
+		return err
 	}
 
 	i.kafkaAddr = broker.Addr
 	i.kafkaVersion = broker.Version
 	i.register(terminate, i.ContainerNames.Kafka, i.ContainerNames.Zookeeper)
+	return nil
+}
+
+// SetupModule starts any module and terminates it on Close
+func (i *Sets) SetupModule(ctx context.Context, module tc.Module) {
+	if i.err != nil {
+		return
+	}
+	i.err = i.setupModule(i.observe(ctx), module)
+}
+
+func (i *Sets) setupModule(ctx context.Context, module tc.Module) error {
+	err := module.Start(ctx)
+	i.mu.Lock()
+	i.modules = append(i.modules, module)
+	i.mu.Unlock()
+	i.register(func() error {
+		ctx, cancel := teardownContext(ctx)
+		defer cancel()
+		return module.Terminate(ctx)
+	})
+	return err
+}
+
+// Containers returns the metadata of the modules started by SetupModule
+func (i *Sets) Containers() []*tc.ContainerConfig {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	containers := make([]*tc.ContainerConfig, 0, len(i.modules))
+	for _, module := range i.modules {
+		if config := module.Container(); config != nil {
+			containers = append(containers, config)
+		}
+	}
+	return containers
 }
 
-func (i *Sets) register(terminate func(), containerName ...string) {
+func (i *Sets) register(terminate func() error, containerName ...string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
 	i.terminates = append(i.terminates, terminate)
 	i.containerNames = append(i.containerNames, containerName...)
 }
@@ -3,6 +3,7 @@ package infra
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/mmadfox/testcontainers"
 
@@ -12,10 +13,13 @@ import (
 func TestSets(t *testing.T) {
 	sets := NewSets()
 	ctx := context.Background()
-	defer sets.Close()
+	defer func() {
+		require.NoError(t, sets.Close())
+	}()
 
-	testcontainers.DropNetwork(sets.ContainerNames.Network)
+	require.NoError(t, testcontainers.DropNetwork(ctx, sets.ContainerNames.Network))
 
+	sets.Preflight(ctx, "mongo", "redis", "kafka")
 	sets.SetupBridgeNetwork(ctx)
 	sets.SetupMongo(ctx)
 	sets.SetupRedis(ctx)
@@ -25,4 +29,20 @@ func TestSets(t *testing.T) {
 	require.NotNil(t, sets.MongoDB())
 	require.NotNil(t, sets.RedisClient())
 	require.NotEmpty(t, sets.KafkaAddr())
+	assertPortIsOpened(t, sets.RedisPort())
+	assertPortIsOpened(t, sets.MongoPort())
+}
+
+func TestNewTestSets(t *testing.T) {
+	sets := NewTestSets(t)
+	require.Regexp(t, `^test-mongo-infra-TestNewTestSets-[0-9a-f]{8}$`, sets.ContainerNames.Mongo)
+	require.Regexp(t, `^test-network-infra-TestNewTestSets-[0-9a-f]{8}$`, sets.ContainerNames.Network)
+	require.NotEqual(t, sets.ContainerNames.Mongo, NewTestSets(t).ContainerNames.Mongo)
+}
+
+func TestSetsStartTimeoutThenClose(t *testing.T) {
+	sets := NewTestSets(t)
+	sets.Require(ServiceNetwork, ServiceRedis)
+	require.NoError(t, sets.Start(context.Background(), 2*time.Minute))
+	require.NoError(t, sets.Close())
 }
@@ -3,19 +3,24 @@ package kafka
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
 	"text/template"
 	"time"
 
-	dockerclient "github.com/docker/docker/client"
+	"github.com/Shopify/sarama"
 	"github.com/docker/go-connections/nat"
 	tc "github.com/mmadfox/testcontainers"
 	tczk "github.com/mmadfox/testcontainers/zookeeper"
 	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// DataPath is the data directory of kafka, put on tmpfs by InMemoryStorage
+const DataPath = "/var/lib/kafka/data"
+
 // Options ...
 type Options struct {
 	tc.ContainerOptions
@@ -23,6 +28,8 @@ type Options struct {
 	KafkaImageTag     string
 	ZookeeperImageTag string
 	ZookeeperName     string
+	// ReadinessProbe replaces MetadataProbe as the check that kafka is ready
+	ReadinessProbe tc.Probe
 }
 
 // Container ...
@@ -44,25 +51,52 @@ type Composed struct {
 }
 
 // Terminate ...
-func (c *Container) Terminate(ctx context.Context) {
-	if c.Container != nil {
-		c.Container.Terminate(ctx)
+func (c *Container) Terminate(ctx context.Context) error {
+	if c.Container == nil {
+		return nil
+	}
+	if err := c.Container.Terminate(ctx); err != nil {
+		return fmt.Errorf("failed to terminate kafka container %s: %w", c.Container.GetContainerID(), err)
 	}
+	return nil
 }
 
-// Terminate ...
-func (c *Composed) Terminate(ctx context.Context) {
+// Terminate stops kafka, zookeeper and removes the network,
+// every step is attempted and the errors are joined
+func (c *Composed) Terminate(ctx context.Context) error {
+	var errs []error
 	if c.Kafka != nil {
-		c.Kafka.Terminate(ctx)
+		errs = append(errs, c.Kafka.Terminate(ctx))
 	}
-
 	if c.Zookeeper != nil {
-		c.Zookeeper.Terminate(ctx)
+		errs = append(errs, c.Zookeeper.Terminate(ctx))
 	}
-
 	if c.Network != nil {
-		c.Network.Remove(ctx)
+		if err := c.Network.Remove(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove kafka network: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Config returns the metadata of the kafka container
+func (c *Composed) Config() *tc.ContainerConfig {
+	if c.Kafka == nil {
+		return nil
 	}
+	return c.Kafka.Config()
+}
+
+// versionPattern matches the first line starting with a version, confluent images
+// print e.g. "7.4.0-ccs (Commit:...)" and apache images "3.5.1", both may be preceded by warnings
+var versionPattern = regexp.MustCompile(`(?m)^(\d+(?:\.\d+)+)`)
+
+func parseKafkaVersion(output string) (string, error) {
+	matches := versionPattern.FindStringSubmatch(output)
+	if len(matches) != 2 {
+		return "", fmt.Errorf("failed to extract version from %q", output)
+	}
+	return matches[1], nil
 }
 
 func (c *Composed) getKafkaVersion(ctx context.Context) error {
@@ -71,13 +105,11 @@ func (c *Composed) getKafkaVersion(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("failed to get kafka version: %v", err)
 	}
-	stdout := versionOutput.Stdout
-	re := regexp.MustCompile(`^([\d.]*)-`)
-	matches := re.FindStringSubmatch(stdout)
-	if len(matches) != 2 {
-		return fmt.Errorf(`failed to extract version from "%q"`, stdout)
+	version, err := parseKafkaVersion(versionOutput.Stdout)
+	if err != nil {
+		return err
 	}
-	c.Kafka.Version = matches[1]
+	c.Kafka.Version = version
 	return nil
 }
 
@@ -137,6 +169,13 @@ mv {{.LogTemplatePath}} /etc/confluent/docker/log4j.properties.template
 // Start ...
 func Start(ctx context.Context, options Options) (Composed, error) {
 	var composed Composed
+	defaults, err := tc.ApplyDefaults("kafka", &options.ContainerOptions)
+	if err != nil {
+		return composed, err
+	}
+	if options.LogLevel == "" {
+		options.LogLevel = defaults.LogLevel
+	}
 	port, err := nat.NewPort("", "9093")
 	if err != nil {
 		return composed, fmt.Errorf("failed to build port: %v", err)
@@ -145,13 +184,13 @@ func Start(ctx context.Context, options Options) (Composed, error) {
 	startScriptPath := "/start.sh"
 	cmd := fmt.Sprintf("while [ ! -f %s ]; do sleep 0.1; done; cat %s && bash %s", startScriptPath, startScriptPath, startScriptPath)
 
-	tag := "latest"
-	if options.KafkaImageTag != "" {
-		tag = options.KafkaImageTag
+	image, err := tc.Image("kafka", options.KafkaImageTag)
+	if err != nil {
+		return composed, err
 	}
 
 	req := testcontainers.ContainerRequest{
-		Image: fmt.Sprintf("confluentinc/cp-kafka:%s", tag),
+		Image: image,
 		Cmd:   []string{"/bin/bash", "-c", cmd},
 		Env: map[string]string{
 			"KAFKA_LISTENERS":                        fmt.Sprintf("PLAINTEXT://0.0.0.0:%d,BROKER://0.0.0.0:9092", port.Int()),
@@ -162,23 +201,29 @@ func Start(ctx context.Context, options Options) (Composed, error) {
 			"KAFKA_OFFSETS_TOPIC_NUM_PARTITIONS":     "1",
 			"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS": "0",
 		},
-		Mounts: testcontainers.Mounts(testcontainers.BindMount("/var/run/docker.sock", "/var/run/docker.sock")),
 		ExposedPorts: []string{
 			string(port),
 		},
 	}
 
-	tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest)
+	if err := tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest, options.MergePolicy); err != nil {
+		return composed, err
+	}
+	tc.WithTestNaming(&req, options.ContainerOptions, "kafka")
+	if err := tc.WithResources(&req, options.ContainerOptions, DataPath); err != nil {
+		return composed, err
+	}
 
 	// create a network
 	if len(req.Networks) < 1 {
-		networkName := fmt.Sprintf("kafka-network-%s", tc.UniqueID())
-		net, err := tc.CreateNetwork(testcontainers.NetworkRequest{
+		networkName := options.ResourceName("kafka-network")
+		net, err := tc.CreateNetwork(ctx, testcontainers.NetworkRequest{
 			Driver:         "bridge",
 			Name:           networkName,
 			Attachable:     true,
 			CheckDuplicate: true,
-		}, 2)
+			Labels:         tc.SessionLabels(options.CreatingTest()),
+		}, 0)
 		if err != nil {
 			return composed, fmt.Errorf("failed to create network: %v", err)
 		}
@@ -199,34 +244,36 @@ func Start(ctx context.Context, options Options) (Composed, error) {
 				AutoRemove:     options.AutoRemove,
 				Name:           options.ZookeeperName,
 			},
+			TestName:        options.TestName,
+			TB:              options.TB,
+			InMemoryStorage: options.InMemoryStorage,
 		},
 		ImageTag: options.ZookeeperImageTag,
 	}
 	zookeeperContainer, err := tczk.Start(ctx, zookeeperOptions)
 	if err != nil {
-		return composed, fmt.Errorf("failed to start zookeeper container: %v", err)
+		return composed, fmt.Errorf("failed to start zookeeper container: %w", err)
 	}
 	composed.Zookeeper = &zookeeperContainer
 
-	kafkaContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
+	startup := tc.NewStartup("kafka")
+	kafkaContainer, err := startup.StartContainer(ctx, req)
 	if err != nil {
-		return composed, fmt.Errorf("failed to start kafka container: %v", err)
+		return composed, err
 	}
 	composed.Kafka = new(Container)
 	composed.Kafka.Container = kafkaContainer
 
-	host, err := kafkaContainer.Host(ctx)
+	startup.Phase(tc.PhaseInspect)
+	host, err := tc.ContainerHost(ctx, kafkaContainer)
 	if err != nil {
-		return composed, fmt.Errorf("failed to get kafka container host: %v", err)
+		return composed, startup.Fail(kafkaContainer, fmt.Errorf("failed to get kafka container host: %v", err))
 	}
 	composed.Kafka.Host = host
 
 	realPort, err := kafkaContainer.MappedPort(ctx, port)
 	if err != nil {
-		return composed, fmt.Errorf("failed to get exposed kafka container port: %v", err)
+		return composed, startup.Fail(kafkaContainer, fmt.Errorf("failed to get exposed kafka container port: %v", err))
 	}
 	composed.Kafka.Port = realPort.Int()
 	composed.Kafka.Brokers = []string{fmt.Sprintf("%s:%d", host, realPort.Int())}
@@ -234,44 +281,79 @@ func Start(ctx context.Context, options Options) (Composed, error) {
 	bootstrapServer := fmt.Sprintf("PLAINTEXT://%s:%d", host, realPort.Int())
 	composed.Kafka.Listeners = []string{bootstrapServer}
 
-	client, err := dockerclient.NewClientWithOpts(dockerclient.FromEnv, dockerclient.WithAPIVersionNegotiation())
+	ips, err := kafkaContainer.ContainerIPs(ctx)
 	if err != nil {
-		return composed, fmt.Errorf("failed to get docker client: %v", err)
+		return composed, startup.Fail(kafkaContainer, fmt.Errorf("failed to get kafka container addresses: %v", err))
 	}
-	id := kafkaContainer.GetContainerID()
-	inspect, err := client.ContainerInspect(context.Background(), id)
-	if err != nil {
-		return composed, fmt.Errorf("failed to inspect container %s: %v", id, err)
-	}
-	for _, endpoint := range inspect.NetworkSettings.Networks {
-		listener := fmt.Sprintf("BROKER://%s:9092", endpoint.IPAddress)
+	for _, ip := range ips {
+		listener := fmt.Sprintf("BROKER://%s:9092", ip)
 		composed.Kafka.Listeners = append(composed.Kafka.Listeners, listener)
 	}
 
+	composed.Kafka.ContainerConfig, err = tc.InspectContainer(ctx, kafkaContainer)
+	if err != nil {
+		return composed, startup.Fail(kafkaContainer, err)
+	}
+
+	startup.Phase("kafka version")
 	err = composed.getKafkaVersion(ctx)
 	if err != nil {
-		return composed, err
+		return composed, startup.Fail(kafkaContainer, err)
 	}
 
+	startup.Phase("start script")
 	err = composed.addStartScript(ctx, startScriptPath, options)
 	if err != nil {
-		return composed, err
+		return composed, startup.Fail(kafkaContainer, err)
 	}
 
-	for i := 0; i < 60; i++ {
-		kafkaState, err := composed.Kafka.Container.State(ctx)
+	startup.Phase("ready")
+	timeout := options.StartupTimeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	probe := options.ReadinessProbe
+	if probe == nil {
+		probe = MetadataProbe()
+	}
+	if err := tc.ForProbe(probe).WithStartupTimeout(timeout).WaitUntilReady(ctx, kafkaContainer); err != nil {
+		return composed, startup.Fail(kafkaContainer, err)
+	}
+	startup.Done()
+	return composed, nil
+}
+
+// MetadataProbe is ready when a metadata request lists a live broker and an elected controller
+func MetadataProbe() tc.Probe {
+	return func(ctx context.Context, target wait.StrategyTarget) error {
+		addr, err := tc.ProbeEndpoint(ctx, target, "9093/tcp")
 		if err != nil {
-			return composed, err
+			return err
 		}
-		zooState, err := composed.Zookeeper.Container.State(ctx)
+		config := sarama.NewConfig()
+		config.Version = sarama.V2_0_0_0
+		config.Net.DialTimeout = 2 * time.Second
+		config.Net.ReadTimeout = 2 * time.Second
+		config.Metadata.Retry.Max = 0
+		client, err := sarama.NewClient([]string{addr}, config)
 		if err != nil {
-			return composed, err
+			return err
+		}
+		defer client.Close()
+		if len(client.Brokers()) == 0 {
+			return errors.New("kafka metadata lists no brokers")
 		}
-		if kafkaState.Running && zooState.Running {
-			break
+		if _, err := client.Controller(); err != nil {
+			return fmt.Errorf("kafka has no controller: %v", err)
 		}
-		time.Sleep(500 * time.Millisecond)
+		return nil
 	}
+}
 
-	return composed, nil
+// NewModule returns a tc.Module starting kafka and zookeeper with options
+func NewModule(options Options) *tc.ModuleOf[*Composed] {
+	return tc.NewModule(func(ctx context.Context) (*Composed, error) {
+		composed, err := Start(ctx, options)
+		return &composed, err
+	})
 }
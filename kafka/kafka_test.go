@@ -23,19 +23,17 @@ func TestKafka(t *testing.T) {
 	defer container.Terminate(ctx)
 
 	// start logger
-	kafkaLogger, err := tc.StartLogger(ctx, container.Kafka.Container)
+	kafkaLogger, err := tc.StartLogger(ctx, container.Kafka.Container, tc.WithLogSink(tc.TestingSink(t)))
 	if err != nil {
 		t.Errorf("failed to start logger for kafka: %v", err)
 	} else {
 		defer kafkaLogger.Stop()
-		go kafkaLogger.LogToStdout()
 	}
-	zkLogger, err := tc.StartLogger(ctx, container.Zookeeper.Container)
+	zkLogger, err := tc.StartLogger(ctx, container.Zookeeper.Container, tc.WithLogSink(tc.TestingSink(t)))
 	if err != nil {
 		t.Errorf("failed to start logger for zookeeper: %v", err)
 	} else {
 		defer zkLogger.Stop()
-		go zkLogger.LogToStdout()
 	}
 
 	topic := "my-topic"
@@ -0,0 +1,41 @@
+package kafka
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mmadfox/testcontainers/tctest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKafkaVersion(t *testing.T) {
+	for output, version := range map[string]string{
+		"7.4.0-ccs (Commit:30969fa33c185e88)\n": "7.4.0",
+		"3.5.1\n":                               "3.5.1",
+		"WARNING: log4j.properties is not found\n3.6.0 (Commit:60e845626d8a465a)\n": "3.6.0",
+	} {
+		got, err := parseKafkaVersion(output)
+		require.NoError(t, err, output)
+		require.Equal(t, version, got)
+	}
+	_, err := parseKafkaVersion("kafka-topics: command not found\n")
+	require.Error(t, err)
+}
+
+func TestGetKafkaVersion(t *testing.T) {
+	ctx := context.Background()
+	container := tctest.NewContainer("kafka")
+	composed := &Composed{Kafka: &Container{Container: container}}
+
+	container.OnExec([]string{"kafka-topics", "--version"}, tctest.ExecResult{
+		Stderr: "WARN unable to find log4j config\n",
+		Stdout: "7.4.0-ccs (Commit:30969fa33c185e88)\n",
+	})
+	require.NoError(t, composed.getKafkaVersion(ctx))
+	require.Equal(t, "7.4.0", composed.Kafka.Version)
+
+	missing := tctest.NewContainer("kafka")
+	missing.OnExec([]string{"kafka-topics"}, tctest.ExecResult{ExitCode: 127, Stderr: "command not found\n"})
+	composed.Kafka.Container = missing
+	require.ErrorContains(t, composed.getKafkaVersion(ctx), "exit code: 127")
+}
@@ -0,0 +1,189 @@
+package testcontainers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	dockerclient "github.com/docker/docker/client"
+)
+
+// LeakGracePeriod is how long VerifyNoLeaks waits for auto removed containers to disappear
+var LeakGracePeriod = 10 * time.Second
+
+// created records the containers, networks and volumes created by this process
+var created = struct {
+	mu         sync.Mutex
+	containers map[string]string
+	networks   map[string]bool
+	volumes    map[string]bool
+}{
+	containers: make(map[string]string),
+	networks:   make(map[string]bool),
+	volumes:    make(map[string]bool),
+}
+
+func trackContainer(id, name string) {
+	created.mu.Lock()
+	defer created.mu.Unlock()
+	created.containers[id] = name
+}
+
+func untrackContainer(id string) {
+	created.mu.Lock()
+	defer created.mu.Unlock()
+	delete(created.containers, id)
+}
+
+func trackNetwork(name string) {
+	created.mu.Lock()
+	defer created.mu.Unlock()
+	created.networks[name] = true
+}
+
+func trackVolume(name string) {
+	created.mu.Lock()
+	defer created.mu.Unlock()
+	created.volumes[name] = true
+}
+
+// LeakError lists what was created by the process and still exists
+type LeakError struct {
+	// Containers are "name (id)"
+	Containers []string
+	Networks   []string
+	Volumes    []string
+}
+
+func (e *LeakError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d containers, %d networks and %d volumes leaked",
+		len(e.Containers), len(e.Networks), len(e.Volumes))
+	for _, group := range []struct {
+		kind  string
+		names []string
+	}{{"container", e.Containers}, {"network", e.Networks}, {"volume", e.Volumes}} {
+		for _, name := range group.names {
+			fmt.Fprintf(&b, "\n  %s %s", group.kind, name)
+		}
+	}
+	return b.String()
+}
+
+// leakClient is the part of the docker client used to look for leaks
+type leakClient interface {
+	ContainerInspect(ctx context.Context, containerID string) (types.ContainerJSON, error)
+	NetworkInspect(ctx context.Context, networkID string, options types.NetworkInspectOptions) (types.NetworkResource, error)
+	VolumeInspect(ctx context.Context, volumeID string) (volume.Volume, error)
+}
+
+// CheckLeaks returns a *LeakError when containers, networks or volumes created
+// by the modules of this process still exist. Containers being removed are waited for
+// up to LeakGracePeriod.
+func CheckLeaks(ctx context.Context) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return checkLeaks(ctx, client, LeakGracePeriod)
+}
+
+func checkLeaks(ctx context.Context, client leakClient, grace time.Duration) error {
+	deadline := time.Now().Add(grace)
+	for {
+		leaks, err := findLeaks(ctx, client)
+		if err != nil || leaks == nil {
+			return err
+		}
+		if time.Now().After(deadline) {
+			return leaks
+		}
+		select {
+		case <-ctx.Done():
+			return leaks
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+func findLeaks(ctx context.Context, client leakClient) (*LeakError, error) {
+	created.mu.Lock()
+	containers := make(map[string]string, len(created.containers))
+	for id, name := range created.containers {
+		containers[id] = name
+	}
+	networks := sortedKeys(created.networks)
+	volumes := sortedKeys(created.volumes)
+	created.mu.Unlock()
+
+	leaks := &LeakError{}
+	for id, name := range containers {
+		_, err := client.ContainerInspect(ctx, id)
+		if dockerclient.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container %s: %v", id, err)
+		}
+		leaks.Containers = append(leaks.Containers, fmt.Sprintf("%s (%.12s)", name, id))
+	}
+	sort.Strings(leaks.Containers)
+	for _, name := range networks {
+		_, err := client.NetworkInspect(ctx, name, types.NetworkInspectOptions{})
+		if dockerclient.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect network %s: %v", name, err)
+		}
+		leaks.Networks = append(leaks.Networks, name)
+	}
+	for _, name := range volumes {
+		_, err := client.VolumeInspect(ctx, name)
+		if dockerclient.IsErrNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect volume %s: %v", name, err)
+		}
+		leaks.Volumes = append(leaks.Volumes, name)
+	}
+	if len(leaks.Containers)+len(leaks.Networks)+len(leaks.Volumes) == 0 {
+		return nil, nil
+	}
+	return leaks, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// VerifyNoLeaks runs the tests and exits, failing the test binary when containers,
+// networks or volumes created by the modules are still present. Use it as TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		testcontainers.VerifyNoLeaks(m)
+//	}
+func VerifyNoLeaks(m *testing.M) {
+	code := m.Run()
+	if err := CheckLeaks(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "testcontainers: %v\n", err)
+		if code == 0 {
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
@@ -0,0 +1,93 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLeakClient knows the resources in exist, removed ones disappear after a number of inspections
+type fakeLeakClient struct {
+	exist    map[string]bool
+	removing map[string]int
+}
+
+func (c *fakeLeakClient) inspect(id string) error {
+	if n, ok := c.removing[id]; ok {
+		if n == 0 {
+			delete(c.exist, id)
+		}
+		c.removing[id] = n - 1
+	}
+	if !c.exist[id] {
+		return errdefs.NotFound(errors.New("no such object: " + id))
+	}
+	return nil
+}
+
+func (c *fakeLeakClient) ContainerInspect(_ context.Context, id string) (types.ContainerJSON, error) {
+	return types.ContainerJSON{}, c.inspect(id)
+}
+
+func (c *fakeLeakClient) NetworkInspect(_ context.Context, id string, _ types.NetworkInspectOptions) (types.NetworkResource, error) {
+	return types.NetworkResource{}, c.inspect(id)
+}
+
+func (c *fakeLeakClient) VolumeInspect(_ context.Context, id string) (volume.Volume, error) {
+	return volume.Volume{}, c.inspect(id)
+}
+
+func TestCheckLeaks(t *testing.T) {
+	created.mu.Lock()
+	saved := created.containers
+	savedNetworks, savedVolumes := created.networks, created.volumes
+	created.containers = make(map[string]string)
+	created.networks = make(map[string]bool)
+	created.volumes = make(map[string]bool)
+	created.mu.Unlock()
+	defer func() {
+		created.mu.Lock()
+		created.containers, created.networks, created.volumes = saved, savedNetworks, savedVolumes
+		created.mu.Unlock()
+	}()
+
+	ctx := context.Background()
+	client := &fakeLeakClient{exist: map[string]bool{}, removing: map[string]int{}}
+	require.NoError(t, checkLeaks(ctx, client, 0))
+
+	trackContainer("0123456789abcdef", "test-mongo")
+	trackContainer("fedcba9876543210", "test-redis")
+	trackContainer("kept", "test-kafka")
+	untrackContainer("kept")
+	trackNetwork("kafka-network-1")
+	trackNetwork("mongo-replicaset-1")
+	trackVolume("shop_data")
+	client.exist = map[string]bool{
+		"0123456789abcdef": true, "fedcba9876543210": true, "kept": true,
+		"kafka-network-1": true, "shop_data": true,
+	}
+	// the redis container is auto removed a moment later
+	client.removing["fedcba9876543210"] = 2
+
+	err := checkLeaks(ctx, client, time.Second)
+	var leaks *LeakError
+	require.True(t, errors.As(err, &leaks))
+	require.Equal(t, []string{"test-mongo (0123456789ab)"}, leaks.Containers)
+	require.Equal(t, []string{"kafka-network-1"}, leaks.Networks)
+	require.Equal(t, []string{"shop_data"}, leaks.Volumes)
+	require.Equal(t, `1 containers, 1 networks and 1 volumes leaked
+  container test-mongo (0123456789ab)
+  network kafka-network-1
+  volume shop_data`, err.Error())
+
+	delete(client.exist, "0123456789abcdef")
+	delete(client.exist, "kafka-network-1")
+	delete(client.exist, "shop_data")
+	require.NoError(t, checkLeaks(ctx, client, 0))
+}
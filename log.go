@@ -2,49 +2,231 @@ package testcontainers
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/testcontainers/testcontainers-go"
 )
 
-// LogCollector ...
+// LogLine is a single line printed by a container
+type LogLine struct {
+	Container string
+	Stream    StreamType
+	Content   string
+	Time      time.Time
+}
+
+// String formats the line prefixed with the container name and stream
+func (l LogLine) String() string {
+	return fmt.Sprintf("[%s] %s: %s", l.Container, l.Stream, l.Content)
+}
+
+// BackpressurePolicy decides what happens to log lines when the sinks cannot keep up
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock makes the container's log producer wait for the sinks
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDrop discards lines while the buffer is full, see LogCollector.Dropped
+	BackpressureDrop
+)
+
+const (
+	defaultLogBufferSize  = 1024
+	defaultLogHistorySize = 10000
+)
+
+// LoggerOption configures a LogCollector started by StartLogger
+type LoggerOption func(logger *LogCollector)
+
+// WithLogSink adds sinks that receive every collected line
+func WithLogSink(sinks ...LogSink) LoggerOption {
+	return func(logger *LogCollector) {
+		logger.sinks = append(logger.sinks, sinks...)
+	}
+}
+
+// WithLogBackpressure sets the policy for a full buffer, BackpressureBlock by default
+func WithLogBackpressure(policy BackpressurePolicy) LoggerOption {
+	return func(logger *LogCollector) {
+		logger.policy = policy
+	}
+}
+
+// WithLogBufferSize sets the number of lines buffered between the container and the sinks
+func WithLogBufferSize(size int) LoggerOption {
+	return func(logger *LogCollector) {
+		logger.bufferSize = size
+	}
+}
+
+// WithLogHistory sets the number of lines kept for WaitForLog, LogsMatching and AssertNoLog,
+// a size of 0 disables the history
+func WithLogHistory(size int) LoggerOption {
+	return func(logger *LogCollector) {
+		logger.historySize = size
+	}
+}
+
+// LogCollector receives the output of a container and dispatches it line by line to its sinks
 type LogCollector struct {
-	LogChan   chan testcontainers.Log
-	container testcontainers.Container
+	container   testcontainers.Container
+	name        string
+	policy      BackpressurePolicy
+	bufferSize  int
+	historySize int
+
+	mu      sync.RWMutex
+	sinks   []LogSink
+	history *RingBuffer
+	waiters map[chan struct{}]struct{}
+
+	lines    chan LogLine
+	done     chan struct{}
+	stopped  chan struct{}
+	stopOnce sync.Once
+	stopErr  error
+	dropped  atomic.Uint64
 }
 
-// Accept ...
+// Accept implements testcontainers.LogConsumer
 func (logger *LogCollector) Accept(l testcontainers.Log) {
-	logger.LogChan <- l
+	stream := Stdout
+	if l.LogType == testcontainers.StderrLog {
+		stream = Stderr
+	}
+	now := time.Now()
+	content := strings.TrimRight(string(l.Content), "\r\n")
+	for _, text := range strings.Split(content, "\n") {
+		line := LogLine{
+			Container: logger.name,
+			Stream:    stream,
+			Content:   strings.TrimSuffix(text, "\r"),
+			Time:      now,
+		}
+		if logger.policy == BackpressureDrop {
+			select {
+			case logger.lines <- line:
+			default:
+				logger.dropped.Add(1)
+			}
+			continue
+		}
+		select {
+		case logger.lines <- line:
+		case <-logger.done:
+			return
+		}
+	}
 }
 
-// Stop ...
-func (logger *LogCollector) Stop() {
-	logger.container.StopLogProducer()
-	close(logger.LogChan)
+// AddSink adds a sink to a running collector, it only receives lines collected from now on
+func (logger *LogCollector) AddSink(sink LogSink) {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.sinks = append(logger.sinks, sink)
 }
 
-// LogToStdout ...
+// Dropped returns the number of lines discarded by BackpressureDrop
+func (logger *LogCollector) Dropped() uint64 {
+	return logger.dropped.Load()
+}
+
+// Stop stops collecting and delivers the buffered lines to the sinks.
+// It is safe to call Stop more than once. Sinks are not closed.
+func (logger *LogCollector) Stop() error {
+	logger.stopOnce.Do(func() {
+		logger.stopErr = logger.container.StopLogProducer()
+		close(logger.done)
+		<-logger.stopped
+	})
+	return logger.stopErr
+}
+
+// LogToStdout prints every line with the standard logger until the collector is stopped.
+//
+// Deprecated: use StartLogger with WithLogSink(StdoutSink()).
 func (logger *LogCollector) LogToStdout() {
+	logger.AddSink(StdoutSink())
+	<-logger.stopped
+}
+
+func (logger *LogCollector) run() {
+	defer close(logger.stopped)
 	for {
-		message, ok := <-logger.LogChan
-		if !ok {
-			return
+		select {
+		case line := <-logger.lines:
+			logger.dispatch(line)
+		case <-logger.done:
+			for {
+				select {
+				case line := <-logger.lines:
+					logger.dispatch(line)
+				default:
+					return
+				}
+			}
 		}
-		log.Print(string(message.Content))
 	}
+}
+
+func (logger *LogCollector) dispatch(line LogLine) {
+	logger.record(line)
+	logger.mu.RLock()
+	defer logger.mu.RUnlock()
+	for _, sink := range logger.sinks {
+		sink.Accept(line)
+	}
+}
 
+func (logger *LogCollector) record(line LogLine) {
+	if logger.history == nil {
+		return
+	}
+	logger.history.Accept(line)
+	logger.mu.RLock()
+	defer logger.mu.RUnlock()
+	for waiter := range logger.waiters {
+		select {
+		case waiter <- struct{}{}:
+		default:
+		}
+	}
 }
 
-// StartLogger ...
-func StartLogger(ctx context.Context, c testcontainers.Container) (LogCollector, error) {
-	logger := LogCollector{
-		LogChan:   make(chan testcontainers.Log, 10),
-		container: c,
+// StartLogger starts collecting the output of a container
+func StartLogger(ctx context.Context, c testcontainers.Container, opts ...LoggerOption) (*LogCollector, error) {
+	name, _ := c.Name(ctx)
+	logger := &LogCollector{
+		container:   c,
+		name:        strings.TrimPrefix(name, "/"),
+		bufferSize:  defaultLogBufferSize,
+		historySize: defaultLogHistorySize,
+		waiters:     make(map[chan struct{}]struct{}),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	for _, fn := range opts {
+		fn(logger)
 	}
+	if logger.bufferSize < 0 {
+		logger.bufferSize = 0
+	}
+	if logger.historySize > 0 {
+		logger.history = NewRingBuffer(logger.historySize)
+	}
+	logger.lines = make(chan LogLine, logger.bufferSize)
+	go logger.run()
 
 	// reversed to avoid "race" since `StartLogProducer` starts a goroutine
-	c.FollowOutput(&logger)
-	err := c.StartLogProducer(ctx)
-	return logger, err
+	c.FollowOutput(logger)
+	if err := c.StartLogProducer(ctx); err != nil {
+		close(logger.done)
+		<-logger.stopped
+		return nil, err
+	}
+	return logger, nil
 }
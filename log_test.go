@@ -0,0 +1,122 @@
+package testcontainers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// logContainer implements the parts of a container used by LogCollector
+type logContainer struct {
+	testcontainers.Container
+	consumer testcontainers.LogConsumer
+	stops    int
+}
+
+func (c *logContainer) Name(context.Context) (string, error) {
+	return "/test-container", nil
+}
+
+func (c *logContainer) FollowOutput(consumer testcontainers.LogConsumer) {
+	c.consumer = consumer
+}
+
+func (c *logContainer) StartLogProducer(context.Context) error {
+	return nil
+}
+
+func (c *logContainer) StopLogProducer() error {
+	c.stops++
+	return nil
+}
+
+func TestLogCollector(t *testing.T) {
+	c := &logContainer{}
+	ring := NewRingBuffer(10)
+	logger, err := StartLogger(context.Background(), c, WithLogSink(ring))
+	require.NoError(t, err)
+
+	c.consumer.Accept(testcontainers.Log{LogType: testcontainers.StdoutLog, Content: []byte("first\r\nsecond\n")})
+	c.consumer.Accept(testcontainers.Log{LogType: testcontainers.StderrLog, Content: []byte("oops\n")})
+
+	require.NoError(t, logger.Stop())
+	require.NoError(t, logger.Stop())
+	require.Equal(t, 1, c.stops)
+
+	lines := ring.Lines()
+	require.Len(t, lines, 3)
+	require.Equal(t, "[test-container] stdout: first", lines[0].String())
+	require.Equal(t, "[test-container] stdout: second", lines[1].String())
+	require.Equal(t, "[test-container] stderr: oops", lines[2].String())
+}
+
+func TestLogCollectorDrop(t *testing.T) {
+	c := &logContainer{}
+	block := make(chan struct{})
+	slow := LogSinkFunc(func(LogLine) { <-block })
+	logger, err := StartLogger(context.Background(), c,
+		WithLogSink(slow),
+		WithLogBackpressure(BackpressureDrop),
+		WithLogBufferSize(1),
+	)
+	require.NoError(t, err)
+
+	// never blocks although the sink does not make progress
+	for i := 0; i < 10; i++ {
+		c.consumer.Accept(testcontainers.Log{LogType: testcontainers.StdoutLog, Content: []byte("line")})
+	}
+	require.GreaterOrEqual(t, logger.Dropped(), uint64(8))
+
+	close(block)
+	require.NoError(t, logger.Stop())
+}
+
+func TestRingBuffer(t *testing.T) {
+	ring := NewRingBuffer(2)
+	require.Empty(t, ring.Lines())
+	for _, content := range []string{"a", "b", "c"} {
+		ring.Accept(LogLine{Content: content})
+	}
+	lines := ring.Lines()
+	require.Len(t, lines, 2)
+	require.Equal(t, "b", lines[0].Content)
+	require.Equal(t, "c", lines[1].Content)
+}
+
+func TestFileSink(t *testing.T) {
+	sink, err := NewFileSink(t.TempDir())
+	require.NoError(t, err)
+	sink.Accept(LogLine{Container: "mongo/1", Stream: Stdout, Content: "ready"})
+	sink.Accept(LogLine{Container: "redis", Stream: Stderr, Content: "warning"})
+	require.NoError(t, sink.Close())
+
+	require.Equal(t, "mongo_1.log", filepath.Base(sink.Path("mongo/1")))
+	b, err := os.ReadFile(sink.Path("mongo/1"))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "stdout: ready\n")
+	b, err = os.ReadFile(sink.Path("redis"))
+	require.NoError(t, err)
+	require.Contains(t, string(b), "stderr: warning\n")
+}
+
+type fakeTestLogger struct {
+	lines []string
+}
+
+func (l *fakeTestLogger) Logf(format string, args ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *fakeTestLogger) Helper() {}
+
+func TestTestingSink(t *testing.T) {
+	var _ TestLogger = t
+	tb := &fakeTestLogger{}
+	TestingSink(tb).Accept(LogLine{Container: "redis", Stream: Stdout, Content: "100% ready"})
+	require.Equal(t, []string{"[redis] stdout: 100% ready"}, tb.lines)
+}
@@ -0,0 +1,105 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// LogMatcher matches the content of a log line, *regexp.Regexp is a LogMatcher
+type LogMatcher interface {
+	MatchString(s string) bool
+}
+
+var _ LogMatcher = (*regexp.Regexp)(nil)
+
+// Contains matches lines containing a literal substring
+func Contains(substr string) LogMatcher {
+	return containsMatcher(substr)
+}
+
+type containsMatcher string
+
+func (m containsMatcher) MatchString(s string) bool {
+	return strings.Contains(s, string(m))
+}
+
+func (m containsMatcher) String() string {
+	return fmt.Sprintf("%q", string(m))
+}
+
+// ErrLoggerStopped is returned by WaitForLog when the collector stops before a line matched
+var ErrLoggerStopped = errors.New("log collector stopped")
+
+// WaitForLog waits until the container prints a matching line.
+// Lines collected before the call are searched first, see WithLogHistory.
+func (logger *LogCollector) WaitForLog(ctx context.Context, pattern LogMatcher) (LogLine, error) {
+	if logger.history == nil {
+		return LogLine{}, errors.New("log history is disabled")
+	}
+	notify := make(chan struct{}, 1)
+	logger.mu.Lock()
+	logger.waiters[notify] = struct{}{}
+	logger.mu.Unlock()
+	defer func() {
+		logger.mu.Lock()
+		delete(logger.waiters, notify)
+		logger.mu.Unlock()
+	}()
+
+	// every line is only searched once
+	var seen uint64
+	for {
+		var lines []LogLine
+		lines, seen = logger.history.since(seen)
+		for _, line := range lines {
+			if pattern.MatchString(line.Content) {
+				return line, nil
+			}
+		}
+
+		select {
+		case <-notify:
+		case <-logger.stopped:
+			// the last lines may have arrived together with the stop
+			lines, _ = logger.history.since(seen)
+			for _, line := range lines {
+				if pattern.MatchString(line.Content) {
+					return line, nil
+				}
+			}
+			return LogLine{}, fmt.Errorf("waiting for %v in %s: %w", pattern, logger.name, ErrLoggerStopped)
+		case <-ctx.Done():
+			return LogLine{}, fmt.Errorf("waiting for %v in %s: %w", pattern, logger.name, ctx.Err())
+		}
+	}
+}
+
+// LogsMatching returns the collected lines that match, oldest first
+func (logger *LogCollector) LogsMatching(pattern LogMatcher) []LogLine {
+	if logger.history == nil {
+		return nil
+	}
+	var matching []LogLine
+	for _, line := range logger.history.Lines() {
+		if pattern.MatchString(line.Content) {
+			matching = append(matching, line)
+		}
+	}
+	return matching
+}
+
+// AssertNoLog returns an error listing every collected line that matches
+func (logger *LogCollector) AssertNoLog(pattern LogMatcher) error {
+	matching := logger.LogsMatching(pattern)
+	if len(matching) == 0 {
+		return nil
+	}
+	lines := make([]string, 0, len(matching))
+	for _, line := range matching {
+		lines = append(lines, line.String())
+	}
+	return fmt.Errorf("expected no line matching %v, found %d:\n%s", pattern, len(matching), strings.Join(lines, "\n"))
+}
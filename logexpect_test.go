@@ -0,0 +1,91 @@
+package testcontainers
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func stdoutLog(content string) testcontainers.Log {
+	return testcontainers.Log{LogType: testcontainers.StdoutLog, Content: []byte(content)}
+}
+
+func TestWaitForLog(t *testing.T) {
+	c := &logContainer{}
+	logger, err := StartLogger(context.Background(), c)
+	require.NoError(t, err)
+	defer logger.Stop()
+
+	// printed before anybody waits for it
+	c.consumer.Accept(stdoutLog("[KafkaServer id=1] started (kafka.server.KafkaServer)\n"))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	line, err := logger.WaitForLog(ctx, Contains("started (kafka.server.KafkaServer)"))
+	require.NoError(t, err)
+	require.Equal(t, Stdout, line.Stream)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.consumer.Accept(stdoutLog("booting\n"))
+		c.consumer.Accept(stdoutLog("Server startup complete; 4 plugins started.\n"))
+	}()
+	line, err = logger.WaitForLog(ctx, regexp.MustCompile(`^Server startup complete; \d+ plugins`))
+	require.NoError(t, err)
+	require.Equal(t, "Server startup complete; 4 plugins started.", line.Content)
+
+	short, cancelShort := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancelShort()
+	_, err = logger.WaitForLog(short, Contains("never printed"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestWaitForLogStopped(t *testing.T) {
+	c := &logContainer{}
+	logger, err := StartLogger(context.Background(), c)
+	require.NoError(t, err)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = logger.Stop()
+	}()
+	_, err = logger.WaitForLog(context.Background(), Contains("never printed"))
+	require.ErrorIs(t, err, ErrLoggerStopped)
+}
+
+func TestWaitForLogHistoryWrap(t *testing.T) {
+	c := &logContainer{}
+	logger, err := StartLogger(context.Background(), c, WithLogHistory(2))
+	require.NoError(t, err)
+	defer logger.Stop()
+
+	go func() {
+		for _, content := range []string{"a\n", "b\n", "c\n", "d\n", "ready\n"} {
+			time.Sleep(10 * time.Millisecond)
+			c.consumer.Accept(stdoutLog(content))
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err = logger.WaitForLog(ctx, Contains("ready"))
+	require.NoError(t, err)
+}
+
+func TestAssertNoLog(t *testing.T) {
+	c := &logContainer{}
+	logger, err := StartLogger(context.Background(), c)
+	require.NoError(t, err)
+
+	c.consumer.Accept(stdoutLog("INFO all good\nERROR disk full\nERROR out of memory\n"))
+	require.NoError(t, logger.Stop())
+
+	errorLine := regexp.MustCompile(`^ERROR`)
+	require.Len(t, logger.LogsMatching(errorLine), 2)
+	err = logger.AssertNoLog(errorLine)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "ERROR disk full")
+	require.NoError(t, logger.AssertNoLog(Contains("panic")))
+}
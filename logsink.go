@@ -0,0 +1,177 @@
+package testcontainers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// LogSink receives the lines collected by a LogCollector.
+// Accept is called from a single goroutine per collector,
+// sinks shared between collectors must be safe for concurrent use.
+type LogSink interface {
+	Accept(line LogLine)
+}
+
+// LogSinkFunc adapts a function to a LogSink
+type LogSinkFunc func(line LogLine)
+
+// Accept implements LogSink
+func (fn LogSinkFunc) Accept(line LogLine) {
+	fn(line)
+}
+
+// StdoutSink prints every line with the standard logger
+func StdoutSink() LogSink {
+	return LogSinkFunc(func(line LogLine) {
+		log.Print(line.String())
+	})
+}
+
+// TestLogger is the part of testing.TB used by TestingSink
+type TestLogger interface {
+	Logf(format string, args ...any)
+	Helper()
+}
+
+// TestingSink logs every line with tb.Logf, tb is usually a testing.TB.
+// The collector must be stopped before the test finishes.
+func TestingSink(tb TestLogger) LogSink {
+	return LogSinkFunc(func(line LogLine) {
+		tb.Helper()
+		tb.Logf("%s", line.String())
+	})
+}
+
+// SlogSink logs every line as an info record with the container and stream as attributes
+func SlogSink(logger *slog.Logger) LogSink {
+	return LogSinkFunc(func(line LogLine) {
+		logger.Info(line.Content,
+			slog.String("container", line.Container),
+			slog.String("stream", line.Stream.String()),
+		)
+	})
+}
+
+// FileSink writes the lines of every container to its own file in a directory
+type FileSink struct {
+	dir   string
+	mu    sync.Mutex
+	files map[string]*os.File
+	err   error
+}
+
+// NewFileSink creates a sink writing to <dir>/<container>.log, dir is created if needed
+func NewFileSink(dir string) (*FileSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory %s: %v", dir, err)
+	}
+	return &FileSink{dir: dir, files: make(map[string]*os.File)}, nil
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+
+// Path returns the file the lines of a container are written to
+func (s *FileSink) Path(container string) string {
+	name := unsafeFileChars.ReplaceAllString(container, "_")
+	if name == "" {
+		name = "container"
+	}
+	return filepath.Join(s.dir, name+".log")
+}
+
+// Accept implements LogSink, the first write error is reported by Close
+func (s *FileSink) Accept(line LogLine) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	f, ok := s.files[line.Container]
+	if !ok {
+		var err error
+		f, err = os.OpenFile(s.Path(line.Container), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.files[line.Container] = f
+	}
+	if _, err := fmt.Fprintf(f, "%s %s: %s\n", line.Time.Format("2006-01-02T15:04:05.000Z07:00"), line.Stream, line.Content); err != nil {
+		s.err = err
+	}
+}
+
+// Close closes all files
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	errs := []error{s.err}
+	for name, f := range s.files {
+		errs = append(errs, f.Close())
+		delete(s.files, name)
+	}
+	return errors.Join(errs...)
+}
+
+// RingBuffer is an in-memory sink keeping the most recent lines
+type RingBuffer struct {
+	mu    sync.Mutex
+	lines []LogLine
+	next  int
+	full  bool
+	total uint64
+}
+
+// NewRingBuffer creates a sink keeping the last size lines
+func NewRingBuffer(size int) *RingBuffer {
+	if size < 1 {
+		size = 1
+	}
+	return &RingBuffer{lines: make([]LogLine, size)}
+}
+
+// Accept implements LogSink
+func (b *RingBuffer) Accept(line LogLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lines[b.next] = line
+	b.total++
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Lines returns the kept lines, oldest first
+func (b *RingBuffer) Lines() []LogLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.ordered()
+}
+
+// since returns the kept lines accepted after the first n lines
+// together with the number of lines accepted so far
+func (b *RingBuffer) since(n uint64) ([]LogLine, uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	lines := b.ordered()
+	if newer := b.total - n; newer < uint64(len(lines)) {
+		lines = lines[uint64(len(lines))-newer:]
+	}
+	return lines, b.total
+}
+
+func (b *RingBuffer) ordered() []LogLine {
+	if !b.full {
+		return append([]LogLine(nil), b.lines[:b.next]...)
+	}
+	lines := make([]LogLine, 0, len(b.lines))
+	lines = append(lines, b.lines[b.next:]...)
+	return append(lines, b.lines[:b.next]...)
+}
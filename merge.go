@@ -1,20 +1,201 @@
 package testcontainers
 
 import (
-	"github.com/imdario/mergo"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
 	"github.com/testcontainers/testcontainers-go"
 )
 
-// MergeRequest ...
-func MergeRequest(c *testcontainers.ContainerRequest, override *testcontainers.ContainerRequest) {
-	if err := mergo.Merge(c, override, mergo.WithOverride); err != nil {
-		panic(err)
+// SliceMerge decides how a slice of the user's request is combined with the module default
+type SliceMerge int
+
+const (
+	// SliceReplace uses the user's slice instead of the default when it is not empty
+	SliceReplace SliceMerge = iota
+	// SliceAppend appends the user's items that are not already in the default
+	SliceAppend
+)
+
+// MergePolicy controls how MergeRequest combines a module request with the user's request.
+// Maps such as Env and Labels are always merged key by key.
+type MergePolicy struct {
+	ExposedPorts SliceMerge
+	// Mounts are appended unless a default mount has the same target, which is replaced
+	Mounts   SliceMerge
+	Networks SliceMerge
+	// DryRun leaves the request untouched and makes MergeRequest return a *MergeReport
+	DryRun bool
+}
+
+// Override is a module default replaced by the user's request
+type Override struct {
+	Field   string
+	Default interface{}
+	Value   interface{}
+}
+
+// String formats the override as Field: default -> value
+func (o Override) String() string {
+	return fmt.Sprintf("%s: %v -> %v", o.Field, o.Default, o.Value)
+}
+
+// MergeReport is returned by MergeRequest when MergePolicy.DryRun is set
+type MergeReport struct {
+	Overrides []Override
+	// Request is the request the module would have started
+	Request testcontainers.ContainerRequest
+}
+
+// Error implements error so that a dry run stops the module before starting anything
+func (r *MergeReport) Error() string {
+	if len(r.Overrides) == 0 {
+		return "merge dry run: no module defaults overridden"
+	}
+	overrides := make([]string, 0, len(r.Overrides))
+	for _, o := range r.Overrides {
+		overrides = append(overrides, o.String())
+	}
+	return fmt.Sprintf("merge dry run: %d module defaults overridden: %s", len(r.Overrides), strings.Join(overrides, ", "))
+}
+
+// MergeRequest merges the non-zero fields of override into the module request c
+func MergeRequest(c *testcontainers.ContainerRequest, override *testcontainers.ContainerRequest, policy MergePolicy) error {
+	if c == nil {
+		return errors.New("failed to merge request: nil request")
+	}
+	if override == nil {
+		override = &testcontainers.ContainerRequest{}
+	}
+
+	merged := *c
+	rest := *override
+	var overrides []Override
+	merged.ExposedPorts = mergeSlice("ExposedPorts", merged.ExposedPorts, rest.ExposedPorts, policy.ExposedPorts, &overrides,
+		func(a, b string) bool { return a == b })
+	merged.Networks = mergeSlice("Networks", merged.Networks, rest.Networks, policy.Networks, &overrides,
+		func(a, b string) bool { return a == b })
+	merged.Mounts = mergeSlice("Mounts", merged.Mounts, rest.Mounts, policy.Mounts, &overrides,
+		func(a, b testcontainers.ContainerMount) bool { return a.Target == b.Target })
+	rest.ExposedPorts, rest.Networks, rest.Mounts = nil, nil, nil
+
+	if err := mergeValue("", reflect.ValueOf(&merged).Elem(), reflect.ValueOf(rest), &overrides); err != nil {
+		return fmt.Errorf("failed to merge request: %v", err)
+	}
+	sort.SliceStable(overrides, func(i, j int) bool { return overrides[i].Field < overrides[j].Field })
+
+	if policy.DryRun {
+		return &MergeReport{Overrides: overrides, Request: merged}
+	}
+	*c = merged
+	return nil
+}
+
+// MergeOptions merges the non-zero fields of override into c,
+// both must be pointers to the same struct type
+func MergeOptions(c interface{}, override interface{}) error {
+	dst, src := reflect.ValueOf(c), reflect.ValueOf(override)
+	if dst.Kind() != reflect.Ptr || dst.IsNil() || dst.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("failed to merge options: %T is not a pointer to a struct", c)
+	}
+	if src.Kind() != reflect.Ptr || src.IsNil() || src.Type() != dst.Type() {
+		return fmt.Errorf("failed to merge options: cannot merge %T into %T", override, c)
+	}
+	return mergeValue("", dst.Elem(), src.Elem(), nil)
+}
+
+func mergeSlice[T any](field string, defaults, user []T, mode SliceMerge, overrides *[]Override, same func(a, b T) bool) []T {
+	if len(user) == 0 {
+		return defaults
+	}
+	if mode == SliceReplace {
+		if len(defaults) > 0 && !reflect.DeepEqual(defaults, user) {
+			*overrides = append(*overrides, Override{Field: field, Default: defaults, Value: user})
+		}
+		return append([]T(nil), user...)
+	}
+
+	merged := append([]T(nil), defaults...)
+next:
+	for _, item := range user {
+		for i := range merged {
+			if same(merged[i], item) {
+				if !reflect.DeepEqual(merged[i], item) {
+					*overrides = append(*overrides, Override{Field: fmt.Sprintf("%s[%d]", field, i), Default: merged[i], Value: item})
+				}
+				merged[i] = item
+				continue next
+			}
+		}
+		merged = append(merged, item)
+	}
+	return merged
+}
+
+// mergeValue sets the non-zero parts of src on dst, maps are merged key by key
+// into a new map so that the module defaults are never modified in place
+func mergeValue(path string, dst, src reflect.Value, overrides *[]Override) error {
+	if src.IsZero() {
+		return nil
+	}
+	if !dst.CanSet() {
+		return fmt.Errorf("%s cannot be set", path)
+	}
+	record := func(field string, def, value reflect.Value) {
+		if overrides != nil {
+			*overrides = append(*overrides, Override{Field: field, Default: def.Interface(), Value: value.Interface()})
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			field := dst.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			name := field.Name
+			if path != "" && !field.Anonymous {
+				name = path + "." + name
+			} else if field.Anonymous {
+				name = path
+			}
+			if err := mergeValue(name, dst.Field(i), src.Field(i), overrides); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		merged := reflect.MakeMapWithSize(dst.Type(), dst.Len()+src.Len())
+		iter := dst.MapRange()
+		for iter.Next() {
+			merged.SetMapIndex(iter.Key(), iter.Value())
+		}
+		keys := src.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, key := range keys {
+			value := src.MapIndex(key)
+			if def := dst.MapIndex(key); def.IsValid() && !reflect.DeepEqual(def.Interface(), value.Interface()) {
+				record(fmt.Sprintf("%s[%v]", path, key), def, value)
+			}
+			merged.SetMapIndex(key, value)
+		}
+		dst.Set(merged)
+	default:
+		if !dst.IsZero() && !equalValues(dst, src) {
+			record(path, dst, src)
+		}
+		dst.Set(src)
 	}
+	return nil
 }
 
-// MergeOptions can merge generic options
-func MergeOptions(c interface{}, override interface{}) {
-	if err := mergo.Merge(c, override, mergo.WithOverride); err != nil {
-		panic(err)
+func equalValues(a, b reflect.Value) bool {
+	if a.Kind() == reflect.Func {
+		// functions are only equal when both are nil
+		return a.IsNil() && b.IsNil()
 	}
+	return reflect.DeepEqual(a.Interface(), b.Interface())
 }
@@ -0,0 +1,121 @@
+package testcontainers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func mongoDefaults() testcontainers.ContainerRequest {
+	return testcontainers.ContainerRequest{
+		Image: "mongo:latest",
+		Env: map[string]string{
+			"MONGO_INITDB_ROOT_USERNAME": "root",
+			"MONGO_INITDB_ROOT_PASSWORD": "secret",
+		},
+		ExposedPorts: []string{"2189:27017"},
+		Mounts:       testcontainers.Mounts(testcontainers.VolumeMount("mongo-data", "/data/db")),
+		WaitingFor:   wait.ForListeningPort("27017"),
+	}
+}
+
+func TestMergeRequestMapsKeyByKey(t *testing.T) {
+	req := mongoDefaults()
+	defaultEnv := req.Env
+	override := testcontainers.ContainerRequest{
+		Name: "test-mongo",
+		Env:  map[string]string{"TZ": "UTC", "MONGO_INITDB_ROOT_PASSWORD": "other"},
+	}
+	require.NoError(t, MergeRequest(&req, &override, MergePolicy{}))
+
+	require.Equal(t, "test-mongo", req.Name)
+	require.Equal(t, "mongo:latest", req.Image)
+	require.Equal(t, map[string]string{
+		"MONGO_INITDB_ROOT_USERNAME": "root",
+		"MONGO_INITDB_ROOT_PASSWORD": "other",
+		"TZ":                         "UTC",
+	}, req.Env)
+	// the module's map is not modified in place
+	require.Len(t, defaultEnv, 2)
+	require.NotNil(t, req.WaitingFor)
+}
+
+func TestMergeRequestSlices(t *testing.T) {
+	override := testcontainers.ContainerRequest{
+		ExposedPorts: []string{"9000/tcp"},
+		Mounts: testcontainers.Mounts(
+			testcontainers.BindMount("/tmp/data", "/data/db"),
+			testcontainers.VolumeMount("mongo-config", "/data/configdb"),
+		),
+	}
+
+	req := mongoDefaults()
+	require.NoError(t, MergeRequest(&req, &override, MergePolicy{}))
+	require.Equal(t, []string{"9000/tcp"}, req.ExposedPorts)
+	require.Len(t, req.Mounts, 2)
+
+	req = mongoDefaults()
+	require.NoError(t, MergeRequest(&req, &override, MergePolicy{ExposedPorts: SliceAppend, Mounts: SliceAppend}))
+	require.Equal(t, []string{"2189:27017", "9000/tcp"}, req.ExposedPorts)
+	require.Len(t, req.Mounts, 2)
+	// a mount with the same target replaces the default
+	require.Equal(t, testcontainers.GenericBindMountSource{HostPath: "/tmp/data"}, req.Mounts[0].Source)
+	require.Equal(t, testcontainers.ContainerMountTarget("/data/configdb"), req.Mounts[1].Target)
+}
+
+func TestMergeRequestDryRun(t *testing.T) {
+	req := mongoDefaults()
+	override := testcontainers.ContainerRequest{
+		Image:        "mongo:6.0",
+		Env:          map[string]string{"MONGO_INITDB_ROOT_USERNAME": "admin", "TZ": "UTC"},
+		ExposedPorts: []string{"27017/tcp"},
+	}
+	err := MergeRequest(&req, &override, MergePolicy{DryRun: true})
+
+	var report *MergeReport
+	require.True(t, errors.As(err, &report))
+	require.Equal(t, []string{
+		"Env[MONGO_INITDB_ROOT_USERNAME]: root -> admin",
+		"ExposedPorts: [2189:27017] -> [27017/tcp]",
+		"Image: mongo:latest -> mongo:6.0",
+	}, overrideStrings(report.Overrides))
+	require.Equal(t, "mongo:6.0", report.Request.Image)
+	require.Contains(t, err.Error(), "3 module defaults overridden")
+
+	// the request is left untouched
+	require.Equal(t, mongoDefaults().Image, req.Image)
+	require.Len(t, req.Env, 2)
+}
+
+func TestMergeRequestNilOverride(t *testing.T) {
+	req := mongoDefaults()
+	require.NoError(t, MergeRequest(&req, nil, MergePolicy{}))
+	require.Equal(t, "mongo:latest", req.Image)
+	require.Error(t, MergeRequest(nil, &req, MergePolicy{}))
+}
+
+func TestMergeOptions(t *testing.T) {
+	type options struct {
+		Port    int
+		Timeout time.Duration
+		Env     map[string]string
+	}
+	c := options{Port: 27017, Env: map[string]string{"A": "1"}}
+	require.NoError(t, MergeOptions(&c, &options{Timeout: time.Minute, Env: map[string]string{"B": "2"}}))
+	require.Equal(t, options{Port: 27017, Timeout: time.Minute, Env: map[string]string{"A": "1", "B": "2"}}, c)
+
+	require.Error(t, MergeOptions(c, &options{}))
+	require.Error(t, MergeOptions(&c, &struct{ Port int }{}))
+}
+
+func overrideStrings(overrides []Override) []string {
+	s := make([]string, 0, len(overrides))
+	for _, o := range overrides {
+		s = append(s, o.String())
+	}
+	return s
+}
@@ -3,6 +3,7 @@ package minio
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/docker/go-connections/nat"
@@ -11,12 +12,17 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// DataPath is the data directory of minio, put on tmpfs by InMemoryStorage
+const DataPath = "/data"
+
 // Options ...
 type Options struct {
 	tc.ContainerOptions
 	ImageTag     string
 	RootUser     string
 	RootPassword string
+	// ReadinessProbe replaces HealthProbe as the check that minio is ready
+	ReadinessProbe tc.Probe
 }
 
 // Container ...
@@ -30,10 +36,14 @@ type Container struct {
 }
 
 // Terminate ...
-func (c *Container) Terminate(ctx context.Context) {
-	if c.Container != nil {
-		c.Container.Terminate(ctx)
+func (c *Container) Terminate(ctx context.Context) error {
+	if c.Container == nil {
+		return nil
+	}
+	if err := c.Container.Terminate(ctx); err != nil {
+		return fmt.Errorf("failed to terminate minio container %s: %w", c.Container.GetContainerID(), err)
 	}
+	return nil
 }
 
 // ConnectionURI ...
@@ -44,6 +54,9 @@ func (c *Container) ConnectionURI() string {
 // Start ...
 func Start(ctx context.Context, options Options) (Container, error) {
 	var container Container
+	if _, err := tc.ApplyDefaults("minio", &options.ContainerOptions); err != nil {
+		return container, err
+	}
 	container.RootUser = options.RootUser
 	container.RootPassword = options.RootPassword
 
@@ -58,9 +71,9 @@ func Start(ctx context.Context, options Options) (Container, error) {
 		env["MINIO_ROOT_PASSWORD"] = options.RootPassword
 	}
 
-	tag := "latest"
-	if options.ImageTag != "" {
-		tag = options.ImageTag
+	image, err := tc.Image("minio", options.ImageTag)
+	if err != nil {
+		return container, err
 	}
 
 	timeout := options.ContainerOptions.StartupTimeout
@@ -69,35 +82,81 @@ func Start(ctx context.Context, options Options) (Container, error) {
 	}
 
 	req := testcontainers.ContainerRequest{
-		Image:        fmt.Sprintf("minio/minio:%s", tag),
+		Image:        image,
 		Env:          env,
 		Cmd:          []string{"server", "/data"},
 		ExposedPorts: []string{string(port)},
-		WaitingFor:   wait.ForListeningPort(port).WithStartupTimeout(timeout),
 	}
+	probe := options.ReadinessProbe
+	if probe == nil {
+		probe = HealthProbe()
+	}
+	req.WaitingFor = tc.ForProbe(probe).WithStartupTimeout(timeout)
 
-	tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest)
+	if err := tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest, options.MergePolicy); err != nil {
+		return container, err
+	}
+	tc.WithTestNaming(&req, options.ContainerOptions, "minio")
+	if err := tc.WithResources(&req, options.ContainerOptions, DataPath); err != nil {
+		return container, err
+	}
 
-	minioContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
+	startup := tc.NewStartup("minio")
+	minioContainer, err := startup.StartContainer(ctx, req)
 	if err != nil {
-		return container, fmt.Errorf("failed to start container: %v", err)
+		return container, err
 	}
+	startup.Phase(tc.PhaseInspect)
 	container.Container = minioContainer
 
-	host, err := minioContainer.Host(ctx)
+	host, err := tc.ContainerHost(ctx, minioContainer)
 	if err != nil {
-		return container, fmt.Errorf("failed to get container host: %v", err)
+		return container, startup.Fail(minioContainer, fmt.Errorf("failed to get container host: %v", err))
 	}
 	container.Host = host
 
 	realPort, err := minioContainer.MappedPort(ctx, port)
 	if err != nil {
-		return container, fmt.Errorf("failed to get exposed container port: %v", err)
+		return container, startup.Fail(minioContainer, fmt.Errorf("failed to get exposed container port: %v", err))
 	}
 	container.Port = uint(realPort.Int())
 
+	container.ContainerConfig, err = tc.InspectContainer(ctx, minioContainer)
+	if err != nil {
+		return container, startup.Fail(minioContainer, err)
+	}
+	startup.Done()
+
 	return container, nil
 }
+
+// HealthProbe is ready when /minio/health/ready answers 200 OK
+func HealthProbe() tc.Probe {
+	return func(ctx context.Context, target wait.StrategyTarget) error {
+		addr, err := tc.ProbeEndpoint(ctx, target, "9000/tcp")
+		if err != nil {
+			return err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/minio/health/ready", nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("minio is not ready: %s", resp.Status)
+		}
+		return nil
+	}
+}
+
+// NewModule returns a tc.Module starting a container with options
+func NewModule(options Options) *tc.ModuleOf[*Container] {
+	return tc.NewModule(func(ctx context.Context) (*Container, error) {
+		container, err := Start(ctx, options)
+		return &container, err
+	})
+}
@@ -0,0 +1,240 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// Module is a pre-configured container of this library,
+// it lets generic tooling start, inspect and stop any module without type switches
+type Module interface {
+	Start(ctx context.Context) error
+	Terminate(ctx context.Context) error
+	Endpoints() []Endpoint
+	Logs(ctx context.Context) (io.ReadCloser, error)
+	Container() *ContainerConfig
+}
+
+// Instance is the result of a module's Start function
+type Instance interface {
+	Terminate(ctx context.Context) error
+	Config() *ContainerConfig
+}
+
+// ErrNotStarted is returned by a Module that has not been started
+var ErrNotStarted = errors.New("module is not started")
+
+// ModuleOf adapts a module's Start function to the Module interface
+type ModuleOf[T Instance] struct {
+	start    func(ctx context.Context) (T, error)
+	instance T
+	started  bool
+}
+
+// NewModule creates a Module started by start, e.g.
+//
+//	tc.NewModule(func(ctx context.Context) (*redis.Container, error) { ... })
+func NewModule[T Instance](start func(ctx context.Context) (T, error)) *ModuleOf[T] {
+	return &ModuleOf[T]{start: start}
+}
+
+// Start starts the container, a module can only be started once
+func (m *ModuleOf[T]) Start(ctx context.Context) error {
+	if m.started {
+		return errors.New("module is already started")
+	}
+	instance, err := m.start(ctx)
+	// keep a partially started instance so that Terminate can clean it up
+	m.instance, m.started = instance, true
+	return err
+}
+
+// Terminate stops the container, it does nothing if the module was not started
+func (m *ModuleOf[T]) Terminate(ctx context.Context) error {
+	if !m.started {
+		return nil
+	}
+	return m.instance.Terminate(ctx)
+}
+
+// Endpoints returns the endpoints of the started container
+func (m *ModuleOf[T]) Endpoints() []Endpoint {
+	if config := m.Container(); config != nil {
+		return config.Endpoints()
+	}
+	return nil
+}
+
+// Logs returns the output of the started container
+func (m *ModuleOf[T]) Logs(ctx context.Context) (io.ReadCloser, error) {
+	config := m.Container()
+	if config == nil {
+		return nil, ErrNotStarted
+	}
+	return config.Logs(ctx)
+}
+
+// Container returns the metadata of the started container, nil if it is not started
+func (m *ModuleOf[T]) Container() *ContainerConfig {
+	if !m.started {
+		return nil
+	}
+	return m.instance.Config()
+}
+
+// Instance returns the module specific result of Start
+func (m *ModuleOf[T]) Instance() T {
+	return m.instance
+}
+
+// Endpoint is a container port and the addresses it is reachable at
+type Endpoint struct {
+	Port nat.Port
+	// External is host:mappedPort reachable from the host, empty if the port is not published
+	External string
+	// Internal are alias:port reachable from the containers on the same networks
+	Internal []string
+}
+
+// ContainerConfig describes a started container
+type ContainerConfig struct {
+	ID        string
+	Name      string
+	Image     string
+	Networks  []string
+	StartedAt time.Time
+	// ExternalEndpoints maps container ports to host:mappedPort
+	ExternalEndpoints map[nat.Port]string
+	// InternalEndpoints maps container ports to alias:port
+	InternalEndpoints map[nat.Port][]string
+
+	container testcontainers.Container
+}
+
+// Config returns c, it makes every module container an Instance
+func (c *ContainerConfig) Config() *ContainerConfig {
+	return c
+}
+
+// Endpoints returns the external and internal endpoints ordered by port
+func (c *ContainerConfig) Endpoints() []Endpoint {
+	ports := make([]nat.Port, 0, len(c.InternalEndpoints))
+	for port := range c.InternalEndpoints {
+		ports = append(ports, port)
+	}
+	for port := range c.ExternalEndpoints {
+		if _, ok := c.InternalEndpoints[port]; !ok {
+			ports = append(ports, port)
+		}
+	}
+	nat.Sort(ports, func(a, b nat.Port) bool {
+		if a.Int() != b.Int() {
+			return a.Int() < b.Int()
+		}
+		return a.Proto() < b.Proto()
+	})
+	endpoints := make([]Endpoint, 0, len(ports))
+	for _, port := range ports {
+		endpoints = append(endpoints, Endpoint{
+			Port:     port,
+			External: c.ExternalEndpoints[port],
+			Internal: c.InternalEndpoints[port],
+		})
+	}
+	return endpoints
+}
+
+// Logs returns the output of the container
+func (c *ContainerConfig) Logs(ctx context.Context) (io.ReadCloser, error) {
+	if c.container == nil {
+		return nil, ErrNotStarted
+	}
+	return c.container.Logs(ctx)
+}
+
+// InspectContainer collects the metadata of a started container
+func InspectContainer(ctx context.Context, c testcontainers.Container) (ContainerConfig, error) {
+	client, err := NewDockerClient()
+	if err != nil {
+		return ContainerConfig{}, err
+	}
+	defer client.Close()
+
+	id := c.GetContainerID()
+	inspect, err := client.ContainerInspect(ctx, id)
+	if err != nil {
+		return ContainerConfig{}, fmt.Errorf("failed to inspect container %s: %v", id, err)
+	}
+	host, err := ContainerHost(ctx, c)
+	if err != nil {
+		return ContainerConfig{}, fmt.Errorf("failed to get container host: %v", err)
+	}
+	config := newContainerConfig(inspect, host)
+	config.container = c
+	return config, nil
+}
+
+func newContainerConfig(inspect types.ContainerJSON, host string) ContainerConfig {
+	config := ContainerConfig{
+		ExternalEndpoints: make(map[nat.Port]string),
+		InternalEndpoints: make(map[nat.Port][]string),
+	}
+	if inspect.ContainerJSONBase != nil {
+		config.ID = inspect.ID
+		config.Name = strings.TrimPrefix(inspect.Name, "/")
+		if inspect.State != nil {
+			config.StartedAt, _ = time.Parse(time.RFC3339Nano, inspect.State.StartedAt)
+		}
+	}
+	var exposed nat.PortSet
+	if inspect.Config != nil {
+		config.Image = inspect.Config.Image
+		exposed = inspect.Config.ExposedPorts
+	}
+	if inspect.NetworkSettings == nil {
+		return config
+	}
+
+	for port, bindings := range inspect.NetworkSettings.Ports {
+		for _, binding := range bindings {
+			if binding.HostPort != "" {
+				config.ExternalEndpoints[port] = net.JoinHostPort(host, binding.HostPort)
+				break
+			}
+		}
+	}
+
+	var aliases []string
+	seen := make(map[string]bool)
+	for name, endpoint := range inspect.NetworkSettings.Networks {
+		config.Networks = append(config.Networks, name)
+		// names do not resolve on the default bridge network
+		if name == "bridge" || endpoint == nil {
+			continue
+		}
+		for _, alias := range append([]string{config.Name}, endpoint.Aliases...) {
+			if alias != "" && !seen[alias] {
+				seen[alias] = true
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	sort.Strings(config.Networks)
+	for port := range exposed {
+		config.InternalEndpoints[port] = nil
+		for _, alias := range aliases {
+			config.InternalEndpoints[port] = append(config.InternalEndpoints[port], net.JoinHostPort(alias, port.Port()))
+		}
+	}
+	return config
+}
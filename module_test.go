@@ -0,0 +1,98 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewContainerConfig(t *testing.T) {
+	inspect := types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:    "4f1c",
+			Name:  "/test-mongo",
+			State: &types.ContainerState{StartedAt: "2023-05-01T10:00:00.5Z"},
+		},
+		Config: &container.Config{
+			Image: "mongo:6.0",
+			ExposedPorts: nat.PortSet{
+				"27017/tcp": {},
+				"9000/tcp":  {},
+			},
+		},
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{
+				Ports: nat.PortMap{
+					"27017/tcp": []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: "49153"}},
+					"9000/tcp":  nil,
+				},
+			},
+			Networks: map[string]*network.EndpointSettings{
+				"bridge":       {Aliases: []string{"ignored"}},
+				"test-network": {Aliases: []string{"mongo", "test-mongo"}},
+			},
+		},
+	}
+	config := newContainerConfig(inspect, "localhost")
+	require.Equal(t, "4f1c", config.ID)
+	require.Equal(t, "test-mongo", config.Name)
+	require.Equal(t, "mongo:6.0", config.Image)
+	require.Equal(t, []string{"bridge", "test-network"}, config.Networks)
+	require.Equal(t, time.Date(2023, 5, 1, 10, 0, 0, 5e8, time.UTC), config.StartedAt)
+
+	require.Equal(t, []Endpoint{
+		{Port: "9000/tcp", Internal: []string{"test-mongo:9000", "mongo:9000"}},
+		{Port: "27017/tcp", External: "localhost:49153", Internal: []string{"test-mongo:27017", "mongo:27017"}},
+	}, config.Endpoints())
+}
+
+type fakeInstance struct {
+	ContainerConfig
+	terminated bool
+}
+
+func (f *fakeInstance) Terminate(context.Context) error {
+	f.terminated = true
+	return nil
+}
+
+func TestModuleOf(t *testing.T) {
+	instance := &fakeInstance{ContainerConfig: ContainerConfig{
+		Name:              "test-redis",
+		ExternalEndpoints: map[nat.Port]string{"6379/tcp": "localhost:3890"},
+	}}
+	var module Module = NewModule(func(context.Context) (*fakeInstance, error) {
+		return instance, nil
+	})
+	require.Nil(t, module.Container())
+	require.Empty(t, module.Endpoints())
+	_, err := module.Logs(context.Background())
+	require.ErrorIs(t, err, ErrNotStarted)
+	require.NoError(t, module.Terminate(context.Background()))
+
+	require.NoError(t, module.Start(context.Background()))
+	require.Error(t, module.Start(context.Background()))
+	require.Equal(t, "test-redis", module.Container().Name)
+	require.Equal(t, []Endpoint{{Port: "6379/tcp", External: "localhost:3890"}}, module.Endpoints())
+	require.NoError(t, module.Terminate(context.Background()))
+	require.True(t, instance.terminated)
+}
+
+func TestModuleOfFailedStart(t *testing.T) {
+	instance := &fakeInstance{}
+	module := NewModule(func(context.Context) (*fakeInstance, error) {
+		return instance, errors.New("failed to start container")
+	})
+	require.Error(t, module.Start(context.Background()))
+
+	// a partially started container is still cleaned up
+	require.NoError(t, module.Terminate(context.Background()))
+	require.True(t, instance.terminated)
+}
@@ -8,18 +8,25 @@ import (
 	tc "github.com/mmadfox/testcontainers"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	mongooptions "go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// DataPath is the data directory of mongo, put on tmpfs by InMemoryStorage
+const DataPath = "/data/db"
+
 const DefaultStartupTimeout = 30 * time.Second
 
 // Options ...
 type Options struct {
 	tc.ContainerOptions
 	User           string
-	Port           int
+	Port           int // pinned host port, 0 lets docker pick a free one, see Container.Port
 	Password       string
 	ImageTag       string
 	StartupTimeout time.Duration
+	// ReadinessProbe replaces PingProbe as the check that mongo is ready
+	ReadinessProbe tc.Probe
 }
 
 // Container ...
@@ -33,10 +40,14 @@ type Container struct {
 }
 
 // Terminate ...
-func (c *Container) Terminate(ctx context.Context) {
-	if c.Container != nil {
-		_ = c.Container.Terminate(ctx)
+func (c *Container) Terminate(ctx context.Context) error {
+	if c.Container == nil {
+		return nil
+	}
+	if err := c.Container.Terminate(ctx); err != nil {
+		return fmt.Errorf("failed to terminate mongo container %s: %w", c.Container.GetContainerID(), err)
 	}
+	return nil
 }
 
 // ConnectionURI ...
@@ -52,18 +63,12 @@ func (c *Container) ConnectionURI() string {
 // Start ...
 func Start(ctx context.Context, options Options) (Container, error) {
 	var container Container
+	if _, err := tc.ApplyDefaults("mongo", &options.ContainerOptions); err != nil {
+		return container, err
+	}
 	container.User = options.User
 	container.Password = options.Password
 
-	if options.Port <= 0 {
-		options.Port = 27017
-	}
-	//
-	//port, err := nat.NewPort("", strconv.Itoa(options.Port))
-	//if err != nil {
-	//	return container, fmt.Errorf("failed to build port: %v", err)
-	//}
-
 	env := make(map[string]string)
 	if options.User != "" && options.Password != "" {
 		env["MONGO_INITDB_ROOT_USERNAME"] = options.User
@@ -75,45 +80,90 @@ func Start(ctx context.Context, options Options) (Container, error) {
 		timeout = 5 * time.Minute // Default timeout
 	}
 
-	tag := "latest"
-	if options.ImageTag != "" {
-		tag = options.ImageTag
+	image, err := tc.Image("mongo", options.ImageTag)
+	if err != nil {
+		return container, err
 	}
 
 	exposedPorts := []string{
-		fmt.Sprintf("%d:%s", options.Port, "27017"),
+		tc.ExposedPort(options.Port, "27017/tcp"),
 	}
 
 	req := testcontainers.ContainerRequest{
-		Image:        fmt.Sprintf("mongo:%s", tag),
+		Image:        image,
 		Env:          env,
 		ExposedPorts: exposedPorts,
 		Cmd:          []string{},
-		WaitingFor:   wait.ForListeningPort("27017").WithStartupTimeout(timeout),
 	}
+	probe := options.ReadinessProbe
+	if probe == nil {
+		probe = PingProbe(options.User, options.Password)
+	}
+	req.WaitingFor = tc.ForProbe(probe).WithStartupTimeout(timeout)
 
-	tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest)
+	if err := tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest, options.MergePolicy); err != nil {
+		return container, err
+	}
+	tc.WithTestNaming(&req, options.ContainerOptions, "mongo")
+	if err := tc.WithResources(&req, options.ContainerOptions, DataPath); err != nil {
+		return container, err
+	}
 
-	mongoContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
+	startup := tc.NewStartup("mongo")
+	mongoContainer, err := startup.StartContainer(ctx, req)
 	if err != nil {
-		return container, fmt.Errorf("failed to start container: %v", err)
+		return container, err
 	}
+	startup.Phase(tc.PhaseInspect)
 	container.Container = mongoContainer
 
-	host, err := mongoContainer.Host(ctx)
+	host, err := tc.ContainerHost(ctx, mongoContainer)
 	if err != nil {
-		return container, fmt.Errorf("failed to get container host: %v", err)
+		return container, startup.Fail(mongoContainer, fmt.Errorf("failed to get container host: %v", err))
 	}
 	container.Host = host
 
 	realPort, err := mongoContainer.MappedPort(ctx, "27017")
 	if err != nil {
-		return container, fmt.Errorf("failed to get exposed container port: %v", err)
+		return container, startup.Fail(mongoContainer, fmt.Errorf("failed to get exposed container port: %v", err))
 	}
 	container.Port = uint(realPort.Int())
 
+	container.ContainerConfig, err = tc.InspectContainer(ctx, mongoContainer)
+	if err != nil {
+		return container, startup.Fail(mongoContainer, err)
+	}
+	startup.Done()
+
 	return container, nil
 }
+
+// PingProbe is ready when mongo authenticates the user, if any, and answers ping
+func PingProbe(user, password string) tc.Probe {
+	return func(ctx context.Context, target wait.StrategyTarget) error {
+		addr, err := tc.ProbeEndpoint(ctx, target, "27017/tcp")
+		if err != nil {
+			return err
+		}
+		opts := mongooptions.Client().
+			ApplyURI(fmt.Sprintf("mongodb://%s/?directConnection=true", addr)).
+			SetServerSelectionTimeout(2 * time.Second)
+		if user != "" && password != "" {
+			opts.SetAuth(mongooptions.Credential{Username: user, Password: password})
+		}
+		client, err := mongodriver.Connect(ctx, opts)
+		if err != nil {
+			return err
+		}
+		defer client.Disconnect(context.Background())
+		return client.Ping(ctx, nil)
+	}
+}
+
+// NewModule returns a tc.Module starting a container with options
+func NewModule(options Options) *tc.ModuleOf[*Container] {
+	return tc.NewModule(func(ctx context.Context) (*Container, error) {
+		container, err := Start(ctx, options)
+		return &container, err
+	})
+}
@@ -2,15 +2,15 @@ package mongo
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"regexp"
 	"strings"
 	"time"
 
 	tc "github.com/mmadfox/testcontainers"
 
 	"github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/wait"
 )
 
 type ReplicaSetContainer struct {
@@ -20,6 +20,9 @@ type ReplicaSetContainer struct {
 	MasterContainerAddr Addr
 	ReplicaSet1Addr     Addr
 	ReplicaSet2Addr     Addr
+	MasterConfig        tc.ContainerConfig
+	ReplicaSet1Config   tc.ContainerConfig
+	ReplicaSet2Config   tc.ContainerConfig
 	ContainerNames      []string
 	NetworkName         string
 	Network             testcontainers.Network
@@ -53,35 +56,52 @@ func (c *ReplicaSetContainer) connectionURIFrom(a Addr, master bool) string {
 	return fmt.Sprintf("mongodb://%s%s/?connect=direct&retryWrites=true&w=majority&readPreference=primaryPreferred&replicaSet=rs0", databaseAuth, databaseHost)
 }
 
-func (c *ReplicaSetContainer) Terminate(ctx context.Context) {
-	if c.MasterContainer != nil {
-		_ = c.MasterContainer.Terminate(ctx)
-	}
-	if c.ReplicaSet1 != nil {
-		_ = c.ReplicaSet1.Terminate(ctx)
-	}
-	if c.ReplicaSet2 != nil {
-		_ = c.ReplicaSet2.Terminate(ctx)
+// Config returns the metadata of the master container
+func (c *ReplicaSetContainer) Config() *tc.ContainerConfig {
+	return &c.MasterConfig
+}
+
+// Terminate stops every member and removes the network,
+// every step is attempted and the errors are joined
+func (c *ReplicaSetContainer) Terminate(ctx context.Context) error {
+	var errs []error
+	for _, container := range []testcontainers.Container{c.MasterContainer, c.ReplicaSet1, c.ReplicaSet2} {
+		if container == nil {
+			continue
+		}
+		if err := container.Terminate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to terminate mongo container %s: %w", container.GetContainerID(), err))
+		}
 	}
 	if c.Network != nil {
-		_ = c.Network.Remove(ctx)
+		if err := c.Network.Remove(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove network %s: %w", c.NetworkName, err))
+		}
 	}
+	return errors.Join(errs...)
 }
 
 func StartReplicaSet(ctx context.Context, options Options) (cont *ReplicaSetContainer, err error) {
+	if _, err := tc.ApplyDefaults("mongo", &options.ContainerOptions); err != nil {
+		return nil, err
+	}
+	if options.StartupTimeout <= 0 {
+		options.StartupTimeout = options.ContainerOptions.StartupTimeout
+	}
 	if options.StartupTimeout <= 0 {
 		options.StartupTimeout = DefaultStartupTimeout
 	}
 
-	tag := "latest"
-	if options.ImageTag != "" {
-		tag = options.ImageTag
+	image, err := tc.Image("mongo", options.ImageTag)
+	if err != nil {
+		return nil, err
 	}
 
 	var m1, rs2, rs3 testcontainers.Container
 	var net testcontainers.Network
 	var networkName string
 	var m1Name, rs2Name, rs3Name string
+	var master, member2, member3 *tc.Startup
 
 	defer func() {
 		if err == nil {
@@ -99,24 +119,25 @@ func StartReplicaSet(ctx context.Context, options Options) (cont *ReplicaSetCont
 		if net != nil {
 			_ = net.Remove(ctx)
 		}
+		// the starts still running failed with the member that failed, see Startup.Fail
+		for _, startup := range []*tc.Startup{master, member2, member3} {
+			if startup != nil {
+				_ = startup.Fail(nil, err)
+			}
+		}
 	}()
 
-	if len(options.Name) == 0 {
-		options.Name = "mongo-replicaset-" + tc.UniqueID()
-	}
-
-	m1Name = options.Name + "-m1"
-	rs2Name = options.Name + "-rs2"
-	rs3Name = options.Name + "-rs3"
+	m1Name, rs2Name, rs3Name = memberNames(options.ContainerOptions)
 
 	if len(options.Networks) < 1 {
-		networkName = fmt.Sprintf("mongo-replicaset-%s", tc.UniqueID())
-		net, err = tc.CreateNetwork(testcontainers.NetworkRequest{
+		networkName = options.ResourceName("mongo-replicaset")
+		net, err = tc.CreateNetwork(ctx, testcontainers.NetworkRequest{
 			Driver:         "bridge",
 			Name:           networkName,
 			Attachable:     true,
 			CheckDuplicate: true,
-		}, 2)
+			Labels:         tc.SessionLabels(options.CreatingTest()),
+		}, 0)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create network: %v", err)
 		}
@@ -124,8 +145,13 @@ func StartReplicaSet(ctx context.Context, options Options) (cont *ReplicaSetCont
 		networkName = options.Networks[0]
 	}
 
+	probe := options.ReadinessProbe
+	if probe == nil {
+		probe = PingProbe("", "")
+	}
+
 	req1 := testcontainers.ContainerRequest{
-		Image: fmt.Sprintf("mongo:%s", tag),
+		Image: image,
 		NetworkAliases: map[string][]string{
 			networkName: {"master"},
 		},
@@ -134,18 +160,20 @@ func StartReplicaSet(ctx context.Context, options Options) (cont *ReplicaSetCont
 		ExposedPorts: []string{"27017/"},
 		Hostname:     "master",
 		Cmd:          []string{"--replSet", "rs0", "--bind_ip", "localhost,master"},
-		WaitingFor:   wait.ForListeningPort("27017").WithStartupTimeout(options.StartupTimeout),
+		WaitingFor:   tc.ForProbe(probe).WithStartupTimeout(options.StartupTimeout),
 	}
-	m1, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req1,
-		Started:          true,
-	})
+	tc.WithSessionLabels(&req1, options.CreatingTest())
+	if err = tc.WithResources(&req1, options.ContainerOptions, DataPath); err != nil {
+		return nil, err
+	}
+	master = tc.NewStartup("mongo replica set master")
+	m1, err = master.StartContainer(ctx, req1)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start container: %v", err)
+		return nil, err
 	}
 
 	req2 := testcontainers.ContainerRequest{
-		Image: fmt.Sprintf("mongo:%s", tag),
+		Image: image,
 		NetworkAliases: map[string][]string{
 			networkName: {"rs2"},
 		},
@@ -154,18 +182,20 @@ func StartReplicaSet(ctx context.Context, options Options) (cont *ReplicaSetCont
 		ExposedPorts: []string{"27017/"},
 		Hostname:     "rs2",
 		Cmd:          []string{"--replSet", "rs0", "--bind_ip", "localhost,rs2"},
-		WaitingFor:   wait.ForListeningPort("27017").WithStartupTimeout(options.StartupTimeout),
+		WaitingFor:   tc.ForProbe(probe).WithStartupTimeout(options.StartupTimeout),
 	}
-	rs2, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req2,
-		Started:          true,
-	})
+	tc.WithSessionLabels(&req2, options.CreatingTest())
+	if err = tc.WithResources(&req2, options.ContainerOptions, DataPath); err != nil {
+		return nil, err
+	}
+	member2 = tc.NewStartup("mongo replica set member rs2")
+	rs2, err = member2.StartContainer(ctx, req2)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start container: %v", err)
+		return nil, err
 	}
 
 	req3 := testcontainers.ContainerRequest{
-		Image:        fmt.Sprintf("mongo:%s", tag),
+		Image:        image,
 		Name:         rs3Name,
 		ExposedPorts: []string{"27017/"},
 		Networks:     []string{networkName},
@@ -174,24 +204,29 @@ func StartReplicaSet(ctx context.Context, options Options) (cont *ReplicaSetCont
 		},
 		Hostname:   "rs3",
 		Cmd:        []string{"--replSet", "rs0", "--bind_ip", "localhost,rs3"},
-		WaitingFor: wait.ForListeningPort("27017").WithStartupTimeout(options.StartupTimeout),
+		WaitingFor: tc.ForProbe(probe).WithStartupTimeout(options.StartupTimeout),
 	}
-	rs3, err = testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req3,
-		Started:          true,
-	})
+	tc.WithSessionLabels(&req3, options.CreatingTest())
+	if err = tc.WithResources(&req3, options.ContainerOptions, DataPath); err != nil {
+		return nil, err
+	}
+	member3 = tc.NewStartup("mongo replica set member rs3")
+	rs3, err = member3.StartContainer(ctx, req3)
 	if err != nil {
-		return nil, fmt.Errorf("failed to start container: %v", err)
+		return nil, err
 	}
 
+	master.Phase(tc.PhaseInspect)
+	member2.Phase(tc.PhaseInspect)
+	member3.Phase(tc.PhaseInspect)
 	if err = m1.Start(ctx); err != nil {
-		return nil, err
+		return nil, master.Fail(m1, err)
 	}
 	if err = rs2.Start(ctx); err != nil {
-		return nil, err
+		return nil, member2.Fail(rs2, err)
 	}
 	if err = rs3.Start(ctx); err != nil {
-		return nil, err
+		return nil, member3.Fail(rs3, err)
 	}
 
 	cont = &ReplicaSetContainer{
@@ -204,92 +239,131 @@ func StartReplicaSet(ctx context.Context, options Options) (cont *ReplicaSetCont
 	}
 
 	if cont.MasterContainerAddr, err = containerAddr(ctx, m1); err != nil {
-		return nil, err
+		return nil, master.Fail(m1, err)
 	}
 	if cont.ReplicaSet1Addr, err = containerAddr(ctx, rs2); err != nil {
-		return nil, err
+		return nil, member2.Fail(rs2, err)
 	}
 	if cont.ReplicaSet2Addr, err = containerAddr(ctx, rs3); err != nil {
-		return nil, err
+		return nil, member3.Fail(rs3, err)
 	}
-
-	if err = runCreateReplicaSet(ctx, m1); err != nil {
-		return nil, err
+	if cont.MasterConfig, err = tc.InspectContainer(ctx, m1); err != nil {
+		return nil, master.Fail(m1, err)
 	}
-	if err = runCheckIsMasterNode(ctx, m1); err != nil {
-		return nil, err
+	if cont.ReplicaSet1Config, err = tc.InspectContainer(ctx, rs2); err != nil {
+		return nil, member2.Fail(rs2, err)
+	}
+	if cont.ReplicaSet2Config, err = tc.InspectContainer(ctx, rs3); err != nil {
+		return nil, member3.Fail(rs3, err)
 	}
+	member2.Done()
+	member3.Done()
 
-	for i := 0; i < 60; i++ {
-		if ok := waitPrimaryNode(ctx, rs3); ok {
-			break
-		}
-		time.Sleep(500 * time.Millisecond)
+	if err = initReplicaSet(ctx, master, m1, rs3, 60, 500*time.Millisecond); err != nil {
+		return nil, master.Fail(m1, err)
 	}
+	master.Done()
 
 	return cont, nil
 }
 
-func runCreateReplicaSet(ctx context.Context, c testcontainers.Container) error {
-	var wrapErr = func(err error) error {
-		return fmt.Errorf("failed to create replica set. error: %w", err)
+// initReplicaSet initiates the replica set on m1, waits until a member is elected primary
+// and m1 reports itself as the master. Right after the initiation m1 is still a secondary.
+func initReplicaSet(ctx context.Context, startup *tc.Startup, m1, member testcontainers.Container, attempts int, interval time.Duration) error {
+	startup.Phase("replica set init")
+	if err := runCreateReplicaSet(ctx, m1); err != nil {
+		return err
+	}
+	startup.Phase("primary election")
+	if !waitPrimaryElection(ctx, member, attempts, interval) {
+		return errors.New("no primary was elected in the replica set")
+	}
+	startup.Phase("master check")
+	var err error
+	for i := 0; i < attempts; i++ {
+		if err = runCheckIsMasterNode(ctx, m1); err == nil {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(interval):
+		}
 	}
+	return err
+}
+
+// okPattern and isMasterPattern match the documents printed by mongosh, e.g. "{ ok: 1 }"
+var (
+	okPattern       = regexp.MustCompile(`\bok: 1\b`)
+	isMasterPattern = regexp.MustCompile(`\bismaster: true\b`)
+)
+
+func runCreateReplicaSet(ctx context.Context, c testcontainers.Container) error {
+	// the master has the highest priority so that it wins the election
 	var cmd = []string{
 		`mongosh`,
 		`--eval`,
-		`'printjson(rs.initiate(
+		`printjson(rs.initiate(
 {_id:"rs0","members":[
-{_id:0,host:"master:27017"},
+{_id:0,host:"master:27017",priority:2},
 {_id:1,host:"rs2:27017"},
 {_id:2,host:"rs3:27017"}
-]}))'`,
+]}))`,
 		`--quiet`,
 	}
-	_, r, err := c.Exec(ctx, cmd)
-	if err != nil {
-		return wrapErr(err)
-	}
-	b, err := io.ReadAll(r)
+	output, err := tc.ExecCmd(ctx, c, cmd)
 	if err != nil {
-		return wrapErr(err)
+		return fmt.Errorf("failed to create replica set. error: %w", err)
 	}
-	if strings.ContainsAny(string(b), "ok") {
-		return nil
+	if !okPattern.MatchString(output.Stdout) {
+		return fmt.Errorf("failed to create replica set: %s", strings.TrimSpace(output.Stdout))
 	}
-	return fmt.Errorf("failed to create replica set")
+	return nil
 }
 
 func runCheckIsMasterNode(ctx context.Context, c testcontainers.Container) error {
-	var wrapErr = func(err error) error {
-		return fmt.Errorf("failed to check master node. error: %w", err)
-	}
-	cmd := []string{`mongosh`, `--eval`, `'printjson(rs.isMaster())'`}
-	_, r, err := c.Exec(ctx, cmd)
+	cmd := []string{`mongosh`, `--eval`, `printjson(rs.isMaster())`, `--quiet`}
+	output, err := tc.ExecCmd(ctx, c, cmd)
 	if err != nil {
-		return wrapErr(err)
+		return fmt.Errorf("failed to check master node. error: %w", err)
 	}
-	b, err := io.ReadAll(r)
-	if err != nil {
-		return wrapErr(err)
+	if !isMasterPattern.MatchString(output.Stdout) {
+		return fmt.Errorf("failed to check master node: %s", strings.TrimSpace(output.Stdout))
 	}
-	if strings.ContainsAny(string(b), "ismaster: true") {
-		return nil
+	return nil
+}
+
+// waitPrimaryElection polls rs.status() until a member is PRIMARY
+func waitPrimaryElection(ctx context.Context, c testcontainers.Container, attempts int, interval time.Duration) bool {
+	for i := 0; i < attempts; i++ {
+		if waitPrimaryNode(ctx, c) {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(interval):
+		}
 	}
-	return fmt.Errorf("failed to check master node")
+	return false
 }
 
 func waitPrimaryNode(ctx context.Context, c testcontainers.Container) bool {
 	cmd := []string{`mongosh`, `--eval`, `rs.status()`, `--quiet`}
-	_, r, _ := c.Exec(ctx, cmd)
-	b, _ := io.ReadAll(r)
-	if strings.Contains(string(b), "PRIMARY") {
+	_, r, err := c.Exec(ctx, cmd)
+	if err != nil {
+		return false
+	}
+	output, _ := tc.ReadCmdOutput(r)
+	if strings.Contains(output.Stdout, "PRIMARY") {
 		return true
 	}
 	return false
 }
 
 func containerAddr(ctx context.Context, c testcontainers.Container) (Addr, error) {
-	host, err := c.Host(ctx)
+	host, err := tc.ContainerHost(ctx, c)
 	if err != nil {
 		return Addr{}, fmt.Errorf("failed to get container host: %v", err)
 	}
@@ -303,3 +377,29 @@ func containerAddr(ctx context.Context, c testcontainers.Container) (Addr, error
 		Port: port,
 	}, nil
 }
+
+// NewReplicaSetModule returns a tc.Module starting a replica set with options
+func NewReplicaSetModule(options Options) *tc.ModuleOf[*ReplicaSetContainer] {
+	return tc.NewModule(func(ctx context.Context) (*ReplicaSetContainer, error) {
+		container, err := StartReplicaSet(ctx, options)
+		if err != nil {
+			// StartReplicaSet already terminated what it started
+			return &ReplicaSetContainer{}, err
+		}
+		return container, nil
+	})
+}
+
+// memberNames names the members after options.Name, or after the test, within tc.MaxNameLength
+func memberNames(options tc.ContainerOptions) (m1, rs2, rs3 string) {
+	switch {
+	case len(options.Name) > 0:
+		return tc.SuffixedName(options.Name, "m1"), tc.SuffixedName(options.Name, "rs2"), tc.SuffixedName(options.Name, "rs3")
+	case options.TB != nil:
+		// the member suffixes are part of the base so that ResourceName keeps room for them
+		return options.ResourceName("mongo-rs-m1"), options.ResourceName("mongo-rs-rs2"), options.ResourceName("mongo-rs-rs3")
+	default:
+		name := options.ResourceName("mongo-replicaset")
+		return tc.SuffixedName(name, "m1"), tc.SuffixedName(name, "rs2"), tc.SuffixedName(name, "rs3")
+	}
+}
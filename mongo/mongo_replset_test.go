@@ -2,9 +2,14 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	tc "github.com/mmadfox/testcontainers"
+	"github.com/mmadfox/testcontainers/tctest"
+	"github.com/testcontainers/testcontainers-go"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -13,7 +18,7 @@ import (
 )
 
 func TestMongoReplicaSet(t *testing.T) {
-	tc.PruneNetwork()
+	require.NoError(t, tc.PruneNetwork(context.Background()))
 
 	opts := Options{}
 	opts.Name = "test"
@@ -32,7 +37,7 @@ func TestMongoReplicaSet(t *testing.T) {
 	}
 
 	for _, contName := range containers {
-		ok, err := tc.ContainerExists(contName)
+		ok, err := tc.ContainerExists(context.Background(), contName)
 		require.NoError(t, err)
 		require.True(t, ok)
 	}
@@ -43,7 +48,7 @@ func TestMongoReplicaSet(t *testing.T) {
 	cont.Terminate(context.Background())
 
 	for _, contName := range containers {
-		ok, err := tc.ContainerExists(contName)
+		ok, err := tc.ContainerExists(context.Background(), contName)
 		require.NoError(t, err)
 		require.False(t, ok)
 	}
@@ -106,3 +111,149 @@ func connect(uri string) (*mongo.Database, error) {
 	}
 	return client.Database("waitPrimaryNode"), nil
 }
+
+type terminateContainer struct {
+	testcontainers.Container
+	id         string
+	err        error
+	terminated bool
+}
+
+func (c *terminateContainer) GetContainerID() string {
+	return c.id
+}
+
+func (c *terminateContainer) Terminate(context.Context) error {
+	c.terminated = true
+	return c.err
+}
+
+func TestReplicaSetTerminateJoinsErrors(t *testing.T) {
+	master := &terminateContainer{id: "m1", err: errors.New("no such container")}
+	rs1 := &terminateContainer{id: "rs2"}
+	rs2 := &terminateContainer{id: "rs3", err: errors.New("timeout")}
+	cont := &ReplicaSetContainer{MasterContainer: master, ReplicaSet1: rs1, ReplicaSet2: rs2}
+
+	err := cont.Terminate(context.Background())
+	require.ErrorIs(t, err, master.err)
+	require.ErrorIs(t, err, rs2.err)
+	require.Contains(t, err.Error(), "m1")
+	require.True(t, master.terminated)
+	require.True(t, rs1.terminated)
+	require.True(t, rs2.terminated)
+}
+
+func TestReplicaSetStatusChecks(t *testing.T) {
+	ctx := context.Background()
+
+	c := tctest.NewContainer("master")
+	c.OnExec([]string{"mongosh", "--eval"}, tctest.ExecResult{Stdout: "{ ok: 1 }\n"})
+	require.NoError(t, runCreateReplicaSet(ctx, c))
+	script := c.Executed()[0][2]
+	require.True(t, strings.HasPrefix(script, "printjson(rs.initiate("), script)
+
+	// "ok" appears in the error document of an already initialized set
+	c = tctest.NewContainer("master")
+	c.OnExec(nil, tctest.ExecResult{Stdout: "MongoServerError: already initialized\n{ ok: 0, codeName: 'AlreadyInitialized' }\n"})
+	require.ErrorContains(t, runCreateReplicaSet(ctx, c), "AlreadyInitialized")
+
+	c = tctest.NewContainer("master")
+	c.OnExec(nil, tctest.ExecResult{ExitCode: 1, Stderr: "MongoNetworkError: connect ECONNREFUSED\n"})
+	require.ErrorContains(t, runCreateReplicaSet(ctx, c), "exit code: 1")
+
+	c = tctest.NewContainer("master")
+	c.OnExec(nil,
+		tctest.ExecResult{Stdout: "{\n  ismaster: false,\n  secondary: true,\n  ok: 1\n}\n"},
+		tctest.ExecResult{Stdout: "{\n  ismaster: true,\n  secondary: false,\n  ok: 1\n}\n"},
+	)
+	require.ErrorContains(t, runCheckIsMasterNode(ctx, c), "ismaster: false")
+	require.NoError(t, runCheckIsMasterNode(ctx, c))
+
+	c = tctest.NewContainer("rs3")
+	c.OnExec(nil,
+		tctest.ExecResult{Stdout: "stateStr: 'STARTUP2'\n"},
+		tctest.ExecResult{Stdout: "stateStr: 'PRIMARY'\n"},
+	)
+	require.True(t, waitPrimaryElection(ctx, c, 3, time.Millisecond))
+	c = tctest.NewContainer("rs3")
+	c.OnExec(nil, tctest.ExecResult{Stdout: "stateStr: 'SECONDARY'\n"})
+	require.False(t, waitPrimaryElection(ctx, c, 3, time.Millisecond))
+	require.Len(t, c.Executed(), 3)
+}
+
+func TestInitReplicaSet(t *testing.T) {
+	ctx := context.Background()
+	isMaster := []string{"mongosh", "--eval", "printjson(rs.isMaster())"}
+
+	m1 := tctest.NewContainer("master")
+	m1.OnExec(isMaster,
+		tctest.ExecResult{Stdout: "{ ismaster: false, secondary: true, ok: 1 }\n"},
+		tctest.ExecResult{Stdout: "{ ismaster: true, secondary: false, ok: 1 }\n"},
+	)
+	m1.OnExec([]string{"mongosh", "--eval"}, tctest.ExecResult{Stdout: "{ ok: 1 }\n"})
+	rs3 := tctest.NewContainer("rs3")
+	rs3.OnExec(nil,
+		tctest.ExecResult{Stdout: "stateStr: 'STARTUP2'\n"},
+		tctest.ExecResult{Stdout: "stateStr: 'PRIMARY'\n"},
+	)
+	startup := tc.NewStartup("mongo replica set master")
+	require.NoError(t, initReplicaSet(ctx, startup, m1, rs3, 5, time.Millisecond))
+
+	var phases []string
+	for _, phase := range startup.Phases() {
+		phases = append(phases, phase.Name)
+	}
+	require.Equal(t, []string{"replica set init", "primary election", "master check"}, phases)
+	// initiate, then isMaster until the master is elected
+	executed := m1.Executed()
+	require.Len(t, executed, 3)
+	require.True(t, strings.HasPrefix(executed[0][2], "printjson(rs.initiate("))
+	require.Equal(t, isMaster, executed[1][:3])
+	require.Len(t, rs3.Executed(), 2)
+
+	// the master is never elected
+	m1 = tctest.NewContainer("master")
+	m1.OnExec(isMaster, tctest.ExecResult{Stdout: "{ ismaster: false, secondary: true, ok: 1 }\n"})
+	m1.OnExec([]string{"mongosh", "--eval"}, tctest.ExecResult{Stdout: "{ ok: 1 }\n"})
+	rs3 = tctest.NewContainer("rs3")
+	rs3.OnExec(nil, tctest.ExecResult{Stdout: "stateStr: 'PRIMARY'\n"})
+	err := initReplicaSet(ctx, tc.NewStartup("mongo"), m1, rs3, 3, time.Millisecond)
+	require.ErrorContains(t, err, "ismaster: false")
+	require.Len(t, m1.Executed(), 4)
+
+	// no primary is elected
+	m1 = tctest.NewContainer("master")
+	m1.OnExec(nil, tctest.ExecResult{Stdout: "{ ok: 1 }\n"})
+	rs3 = tctest.NewContainer("rs3")
+	rs3.OnExec(nil, tctest.ExecResult{Stdout: "stateStr: 'SECONDARY'\n"})
+	err = initReplicaSet(ctx, tc.NewStartup("mongo"), m1, rs3, 3, time.Millisecond)
+	require.ErrorContains(t, err, "no primary was elected")
+	require.Len(t, m1.Executed(), 1)
+}
+
+func TestMemberNames(t *testing.T) {
+	long := tc.ResourceName(t, strings.Repeat("mongo", 20))
+	require.Len(t, long, tc.MaxNameLength)
+
+	var options tc.ContainerOptions
+	options.Name = long
+	m1, rs2, rs3 := memberNames(options)
+	for _, name := range []string{m1, rs2, rs3} {
+		require.LessOrEqual(t, len(name), tc.MaxNameLength, name)
+	}
+	// the random suffix of the name is kept
+	require.True(t, strings.HasSuffix(m1, long[len(long)-9:]+"-m1"), m1)
+	require.True(t, strings.HasSuffix(rs3, long[len(long)-9:]+"-rs3"), rs3)
+
+	t.Run(strings.Repeat("long", 30), func(t *testing.T) {
+		m1, rs2, rs3 := memberNames(tc.ContainerOptions{TB: t})
+		require.True(t, strings.HasPrefix(m1, "mongo-rs-m1-"), m1)
+		require.True(t, strings.HasPrefix(rs2, "mongo-rs-rs2-"), rs2)
+		for _, name := range []string{m1, rs2, rs3} {
+			require.LessOrEqual(t, len(name), tc.MaxNameLength, name)
+		}
+	})
+
+	m1, _, _ = memberNames(tc.ContainerOptions{})
+	require.Regexp(t, `^mongo-replicaset-[0-9a-f-]{36}-m1$`, m1)
+}
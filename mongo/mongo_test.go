@@ -26,12 +26,11 @@ func TestMongo(t *testing.T) {
 	defer container.Terminate(ctx)
 
 	// start logger
-	logger, err := tc.StartLogger(ctx, container.Container)
+	logger, err := tc.StartLogger(ctx, container.Container, tc.WithLogSink(tc.TestingSink(t)))
 	if err != nil {
 		t.Errorf("failed to start logger: %v", err)
 	} else {
 		defer logger.Stop()
-		go logger.LogToStdout()
 	}
 
 	// connect to the database
@@ -0,0 +1,103 @@
+package testcontainers
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// MaxNameLength bounds the names built by ResourceName, a DNS label so that they are valid hostnames
+const MaxNameLength = 63
+
+// invalidNameChars are the runs of characters docker does not accept in container and network names
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_.-]+`)
+
+// ResourceName names a container or network after the test that owns it:
+// base, the package and the sanitized name of tb followed by a short random suffix,
+// e.g. "mongo-infra-TestSets-replica_set-3f2a9c1b". The test part, then base, are cut to fit MaxNameLength.
+// Without tb the name is base followed by UniqueID.
+func ResourceName(tb testing.TB, base string) string {
+	base = strings.TrimSuffix(base, "-")
+	if tb == nil {
+		id := UniqueID()
+		return cutName(base, MaxNameLength-len(id)-1) + "-" + id
+	}
+	suffix := UniqueID()[:8]
+	base = cutName(base, MaxNameLength-len(suffix)-1)
+	owner := sanitizeName(testPackage() + "-" + tb.Name())
+	owner = cutName(owner, MaxNameLength-len(base)-len(suffix)-2)
+	parts := []string{}
+	for _, part := range []string{base, owner, suffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// SuffixedName appends "-" and suffix to name, e.g. for the members of a replica set.
+// A long name is shortened before its last part, e.g. the random suffix of ResourceName,
+// so that the result fits MaxNameLength.
+func SuffixedName(name, suffix string) string {
+	room := MaxNameLength - len(suffix) - 1
+	if len(name) <= room {
+		return name + "-" + suffix
+	}
+	if i := strings.LastIndexAny(name, "-_."); i > 0 {
+		if head := cutName(name[:i], room-(len(name)-i)); head != "" {
+			return head + name[i:] + "-" + suffix
+		}
+	}
+	return cutName(name, room) + "-" + suffix
+}
+
+// cutName cuts name to at most n characters without leaving a trailing separator
+func cutName(name string, n int) string {
+	if len(name) <= n {
+		return name
+	}
+	return strings.TrimRight(name[:max(n, 0)], "-_.")
+}
+
+// ResourceName names a container or network of the module, see ResourceName
+func (o ContainerOptions) ResourceName(base string) string {
+	return ResourceName(o.TB, base)
+}
+
+// CreatingTest is the test stamped on the resources of the module, TestName or the name of TB
+func (o ContainerOptions) CreatingTest() string {
+	if o.TestName == "" && o.TB != nil {
+		return o.TB.Name()
+	}
+	return o.TestName
+}
+
+// WithTestNaming names a container request after the test of the options when it has no name
+// and stamps it with the session labels
+func WithTestNaming(req *testcontainers.ContainerRequest, options ContainerOptions, base string) {
+	if req.Name == "" && options.TB != nil {
+		req.Name = options.ResourceName(base)
+	}
+	WithSessionLabels(req, options.CreatingTest())
+}
+
+// testPackage is the name of the package under test, go test names the binary <package>.test
+// and runs it in the package directory
+func testPackage() string {
+	if name := filepath.Base(os.Args[0]); strings.Contains(name, ".test") {
+		return name[:strings.Index(name, ".test")]
+	}
+	if dir, err := os.Getwd(); err == nil {
+		return filepath.Base(dir)
+	}
+	return ""
+}
+
+func sanitizeName(name string) string {
+	name = invalidNameChars.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-_.")
+}
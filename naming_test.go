@@ -0,0 +1,55 @@
+package testcontainers
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceName(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+	name := ResourceName(t, "test-mongo")
+	require.Regexp(t, `^test-mongo-testcontainers-TestResourceName-[0-9a-f]{8}$`, name)
+	require.NotEqual(t, name, ResourceName(t, "test-mongo"))
+
+	t.Run("replica set/v2:ü", func(t *testing.T) {
+		name := ResourceName(t, "mongo-")
+		require.Regexp(t, valid, name)
+		require.True(t, strings.HasPrefix(name, "mongo-testcontainers-TestResourceName-replica_set-v2-"), name)
+	})
+
+	t.Run(strings.Repeat("long", 30), func(t *testing.T) {
+		name := ResourceName(t, "kafka-network")
+		require.Regexp(t, valid, name)
+		require.Len(t, name, MaxNameLength)
+		require.True(t, strings.HasPrefix(name, "kafka-network-testcontainers-TestResourceName-longlong"), name)
+	})
+
+	t.Run("long base", func(t *testing.T) {
+		name := ResourceName(t, strings.Repeat("network", 20))
+		require.Regexp(t, valid, name)
+		require.Len(t, name, MaxNameLength)
+		require.True(t, strings.HasPrefix(name, "networknetwork"), name)
+		require.Regexp(t, `[a-z]-[0-9a-f]{8}$`, name)
+	})
+
+	require.Regexp(t, `^redis-[0-9a-f-]{36}$`, ResourceName(nil, "redis"))
+	name = ResourceName(nil, strings.Repeat("redis", 20))
+	require.Len(t, name, MaxNameLength)
+	require.Regexp(t, `^redisredis[a-z]*-[0-9a-f-]{36}$`, name)
+}
+
+func TestSuffixedName(t *testing.T) {
+	require.Equal(t, "mongo-m1", SuffixedName("mongo", "m1"))
+
+	long := strings.Repeat("a", 50) + "-" + strings.Repeat("b", 20) + "-3f2a9c1b"
+	name := SuffixedName(long, "rs2")
+	require.Equal(t, strings.Repeat("a", 50)+"-3f2a9c1b-rs2", name)
+
+	// a last part too long to keep is cut like the rest of the name
+	name = SuffixedName(strings.Repeat("x", 70), "m1")
+	require.Equal(t, strings.Repeat("x", 60)+"-m1", name)
+}
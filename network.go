@@ -2,32 +2,286 @@ package testcontainers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/cenkalti/backoff/v4"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
 	"github.com/testcontainers/testcontainers-go"
 )
 
-// CreateNetwork creates a docker container network
-func CreateNetwork(request testcontainers.NetworkRequest, timeoutMin time.Duration) (net testcontainers.Network, err error) {
+// CreateNetwork creates a docker container network stamped with the session labels,
+// failed attempts are retried until timeout, Config.NetworkTimeout when 0, or until ctx is done
+func CreateNetwork(ctx context.Context, request testcontainers.NetworkRequest, timeout time.Duration) (net testcontainers.Network, err error) {
+	request.Labels = withLabels(request.Labels, SessionLabels(""))
+
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = config.NetworkTimeout
+	}
+	if request.ReaperOptions, err = withReaperImage(request.ReaperOptions); err != nil {
+		return nil, err
+	}
+
+	provider, err := newDockerProvider()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create docker network: %v", err)
+	}
 
 	createNetwork := func() error {
 		var err error
-		net, err = testcontainers.GenericNetwork(context.Background(), testcontainers.GenericNetworkRequest{
-			NetworkRequest: request,
-		})
-		return err
+		net, err = provider.CreateNetwork(ctx, request)
+		if err != nil {
+			err = fmt.Errorf("%w: failed to create network", err)
+		}
+		return permanentNetworkError(err)
 	}
 
-	bo := backoff.NewExponentialBackOff()
-	bo.Multiplier = 1.2
-	bo.InitialInterval = 5 * time.Second
-	bo.MaxElapsedTime = timeoutMin * time.Minute
-	err = backoff.Retry(createNetwork, bo)
+	err = backoff.Retry(createNetwork, newNetworkBackOff(ctx, config.Backoff, timeout))
 	if err != nil {
 		err = fmt.Errorf("failed to create docker network: %v", err)
 		return
 	}
+	trackNetwork(request.Name)
 	return
 }
+
+func newNetworkBackOff(ctx context.Context, config BackoffConfig, timeout time.Duration) backoff.BackOff {
+	bo := backoff.NewExponentialBackOff()
+	bo.Multiplier = config.Multiplier
+	bo.InitialInterval = config.InitialInterval
+	bo.MaxInterval = config.MaxInterval
+	bo.MaxElapsedTime = timeout
+	return backoff.WithContext(bo, ctx)
+}
+
+// NetworkOptions describes a network created by a NetworkManager
+type NetworkOptions struct {
+	Name string
+	// Driver is bridge by default
+	Driver string
+	// Subnet in CIDR notation, e.g. 172.28.0.0/16, and the Gateway inside it are optional
+	Subnet  string
+	Gateway string
+	// Internal networks have no egress to the outside world
+	Internal bool
+	Labels   map[string]string
+}
+
+// networkClient is the part of the docker client used by NetworkManager
+type networkClient interface {
+	NetworkCreate(ctx context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error)
+	NetworkConnect(ctx context.Context, networkID, containerID string, config *network.EndpointSettings) error
+	NetworkDisconnect(ctx context.Context, networkID, containerID string, force bool) error
+	NetworkInspect(ctx context.Context, networkID string, options types.NetworkInspectOptions) (types.NetworkResource, error)
+	NetworkRemove(ctx context.Context, networkID string) error
+	Close() error
+}
+
+// NetworkManager creates networks and attaches running containers to them.
+// A network created by the manager is removed once the last container detaches.
+type NetworkManager struct {
+	client  networkClient
+	timeout time.Duration
+	backoff BackoffConfig
+
+	mu sync.Mutex
+	// networks holds the members of the created networks by name, ids maps their IDs to the names
+	networks map[string]map[string]struct{}
+	ids      map[string]string
+}
+
+// NewNetworkManager creates a manager retrying docker calls for up to timeout,
+// Config.NetworkTimeout when 0
+func NewNetworkManager(timeout time.Duration) (*NetworkManager, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = config.NetworkTimeout
+	}
+	client, err := NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	m := newNetworkManager(client, timeout)
+	m.backoff = config.Backoff
+	return m, nil
+}
+
+func newNetworkManager(client networkClient, timeout time.Duration) *NetworkManager {
+	return &NetworkManager{
+		client:   client,
+		timeout:  timeout,
+		backoff:  defaultConfig().Backoff,
+		networks: make(map[string]map[string]struct{}),
+		ids:      make(map[string]string),
+	}
+}
+
+// Create creates a network stamped with the session labels and returns its ID
+func (m *NetworkManager) Create(ctx context.Context, options NetworkOptions) (string, error) {
+	if options.Name == "" {
+		return "", errors.New("failed to create network: name is required")
+	}
+	request := types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         options.Driver,
+		Internal:       options.Internal,
+		Attachable:     true,
+		Labels:         withLabels(options.Labels, SessionLabels("")),
+	}
+	if request.Driver == "" {
+		request.Driver = "bridge"
+	}
+	if options.Subnet != "" || options.Gateway != "" {
+		request.IPAM = &network.IPAM{
+			Config: []network.IPAMConfig{{Subnet: options.Subnet, Gateway: options.Gateway}},
+		}
+	}
+
+	var id string
+	create := func() error {
+		resp, err := m.client.NetworkCreate(ctx, options.Name, request)
+		if err != nil {
+			return permanentNetworkError(err)
+		}
+		id = resp.ID
+		return nil
+	}
+	if err := backoff.Retry(create, newNetworkBackOff(ctx, m.backoff, m.timeout)); err != nil {
+		return "", fmt.Errorf("failed to create network %s: %v", options.Name, err)
+	}
+
+	trackNetwork(options.Name)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.networks[options.Name] = make(map[string]struct{})
+	m.ids[id] = options.Name
+	return id, nil
+}
+
+// name returns the name of a network created by the manager referred to by name or ID
+func (m *NetworkManager) name(nameOrID string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if name, ok := m.ids[nameOrID]; ok {
+		return name
+	}
+	return nameOrID
+}
+
+// Connect attaches a running container to a network, given by name or ID, under the given aliases
+func (m *NetworkManager) Connect(ctx context.Context, nameOrID, containerID string, aliases ...string) error {
+	networkName := m.name(nameOrID)
+	err := m.client.NetworkConnect(ctx, networkName, containerID, &network.EndpointSettings{Aliases: aliases})
+	if err != nil {
+		return fmt.Errorf("failed to connect container %s to network %s: %v", containerID, networkName, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if members, ok := m.networks[networkName]; ok {
+		members[containerID] = struct{}{}
+	}
+	return nil
+}
+
+// Disconnect detaches a container from a network, given by name or ID.
+// A network created by the manager is removed when no container is attached anymore.
+func (m *NetworkManager) Disconnect(ctx context.Context, nameOrID, containerID string) error {
+	networkName := m.name(nameOrID)
+	err := m.client.NetworkDisconnect(ctx, networkName, containerID, true)
+	if err != nil && !dockerclient.IsErrNotFound(err) {
+		return fmt.Errorf("failed to disconnect container %s from network %s: %v", containerID, networkName, err)
+	}
+
+	m.mu.Lock()
+	members, managed := m.networks[networkName]
+	delete(members, containerID)
+	last := managed && len(members) == 0
+	m.mu.Unlock()
+	if !last {
+		return nil
+	}
+
+	// containers attached without the manager, e.g. by their request, keep the network alive
+	resource, err := m.client.NetworkInspect(ctx, networkName, types.NetworkInspectOptions{})
+	if err != nil {
+		if dockerclient.IsErrNotFound(err) {
+			m.forget(networkName)
+			return nil
+		}
+		return fmt.Errorf("failed to inspect network %s: %v", networkName, err)
+	}
+	if len(resource.Containers) > 0 {
+		return nil
+	}
+	return m.Remove(ctx, networkName)
+}
+
+// Remove removes a network, given by name or ID. A network that does not exist is not an error.
+func (m *NetworkManager) Remove(ctx context.Context, nameOrID string) error {
+	networkName := m.name(nameOrID)
+	remove := func() error {
+		err := m.client.NetworkRemove(ctx, networkName)
+		if err != nil && !dockerclient.IsErrNotFound(err) {
+			return err
+		}
+		return nil
+	}
+	if err := backoff.Retry(remove, newNetworkBackOff(ctx, m.backoff, m.timeout)); err != nil {
+		return fmt.Errorf("failed to remove network %s: %v", networkName, err)
+	}
+	m.forget(networkName)
+	return nil
+}
+
+// Close removes the remaining networks created by the manager and closes the docker client
+func (m *NetworkManager) Close(ctx context.Context) error {
+	m.mu.Lock()
+	names := make([]string, 0, len(m.networks))
+	for name := range m.networks {
+		names = append(names, name)
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, name := range names {
+		errs = append(errs, m.Remove(ctx, name))
+	}
+	errs = append(errs, m.client.Close())
+	return errors.Join(errs...)
+}
+
+func (m *NetworkManager) forget(networkName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.networks, networkName)
+	for id, name := range m.ids {
+		if name == networkName {
+			delete(m.ids, id)
+		}
+	}
+}
+
+// permanentNetworkError stops the retries on errors that do not go away,
+// e.g. a name conflict, an invalid subnet or an unknown driver
+func permanentNetworkError(err error) error {
+	// errdefs only follows Cause, testcontainers-go wraps with %w
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if errdefs.IsConflict(e) || errdefs.IsInvalidParameter(e) || errdefs.IsNotFound(e) || errdefs.IsForbidden(e) {
+			return backoff.Permanent(err)
+		}
+	}
+	return err
+}
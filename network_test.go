@@ -0,0 +1,198 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNetworkClient keeps networks and their containers in memory
+type fakeNetworkClient struct {
+	createErr error
+	creates   int
+	requests  map[string]types.NetworkCreate
+	members   map[string]map[string][]string
+	closed    bool
+}
+
+func newFakeNetworkClient() *fakeNetworkClient {
+	return &fakeNetworkClient{
+		requests: make(map[string]types.NetworkCreate),
+		members:  make(map[string]map[string][]string),
+	}
+}
+
+func (c *fakeNetworkClient) NetworkCreate(_ context.Context, name string, options types.NetworkCreate) (types.NetworkCreateResponse, error) {
+	c.creates++
+	if c.createErr != nil {
+		return types.NetworkCreateResponse{}, c.createErr
+	}
+	c.requests[name] = options
+	c.members[name] = make(map[string][]string)
+	return types.NetworkCreateResponse{ID: "id-" + name}, nil
+}
+
+func (c *fakeNetworkClient) NetworkConnect(_ context.Context, name, containerID string, config *network.EndpointSettings) error {
+	members, ok := c.members[name]
+	if !ok {
+		return errdefs.NotFound(errors.New("network not found"))
+	}
+	members[containerID] = config.Aliases
+	return nil
+}
+
+func (c *fakeNetworkClient) NetworkDisconnect(_ context.Context, name, containerID string, _ bool) error {
+	members, ok := c.members[name]
+	if !ok {
+		return errdefs.NotFound(errors.New("network not found"))
+	}
+	delete(members, containerID)
+	return nil
+}
+
+func (c *fakeNetworkClient) NetworkInspect(_ context.Context, name string, _ types.NetworkInspectOptions) (types.NetworkResource, error) {
+	members, ok := c.members[name]
+	if !ok {
+		return types.NetworkResource{}, errdefs.NotFound(errors.New("network not found"))
+	}
+	resource := types.NetworkResource{Name: name, Labels: c.requests[name].Labels, Containers: make(map[string]types.EndpointResource)}
+	for id := range members {
+		resource.Containers[id] = types.EndpointResource{}
+	}
+	return resource, nil
+}
+
+func (c *fakeNetworkClient) NetworkRemove(_ context.Context, name string) error {
+	if _, ok := c.members[name]; !ok {
+		return errdefs.NotFound(errors.New("network not found"))
+	}
+	delete(c.members, name)
+	return nil
+}
+
+func (c *fakeNetworkClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestNetworkManager(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeNetworkClient()
+	manager := newNetworkManager(client, time.Second)
+
+	id, err := manager.Create(ctx, NetworkOptions{
+		Name:     "test-network",
+		Subnet:   "172.28.0.0/16",
+		Gateway:  "172.28.0.1",
+		Internal: true,
+		Labels:   map[string]string{"team": "storage"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "id-test-network", id)
+
+	request := client.requests["test-network"]
+	require.Equal(t, "bridge", request.Driver)
+	require.True(t, request.Internal)
+	require.Equal(t, []network.IPAMConfig{{Subnet: "172.28.0.0/16", Gateway: "172.28.0.1"}}, request.IPAM.Config)
+	require.Equal(t, "storage", request.Labels["team"])
+	require.Equal(t, SessionID(), request.Labels[LabelSessionID])
+
+	require.NoError(t, manager.Connect(ctx, "test-network", "mongo", "master", "db"))
+	require.NoError(t, manager.Connect(ctx, "test-network", "redis"))
+	require.Equal(t, []string{"master", "db"}, client.members["test-network"]["mongo"])
+
+	require.NoError(t, manager.Disconnect(ctx, "test-network", "mongo"))
+	require.Contains(t, client.members, "test-network")
+
+	// the last detach removes the network
+	require.NoError(t, manager.Disconnect(ctx, "test-network", "redis"))
+	require.NotContains(t, client.members, "test-network")
+
+	require.NoError(t, manager.Close(ctx))
+	require.True(t, client.closed)
+}
+
+func TestNetworkManagerKeepsForeignMembers(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeNetworkClient()
+	manager := newNetworkManager(client, time.Second)
+
+	_, err := manager.Create(ctx, NetworkOptions{Name: "shared"})
+	require.NoError(t, err)
+	require.NoError(t, manager.Connect(ctx, "shared", "kafka"))
+	// attached by its container request, not by the manager
+	client.members["shared"]["zookeeper"] = nil
+
+	require.NoError(t, manager.Disconnect(ctx, "shared", "kafka"))
+	require.Contains(t, client.members, "shared")
+
+	require.NoError(t, manager.Close(ctx))
+	require.NotContains(t, client.members, "shared")
+}
+
+func TestNetworkManagerCreateHonoursContext(t *testing.T) {
+	client := newFakeNetworkClient()
+	client.createErr = errors.New("daemon busy")
+	manager := newNetworkManager(client, time.Hour)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := manager.Create(ctx, NetworkOptions{Name: "busy"})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.GreaterOrEqual(t, client.creates, 1)
+
+	// the duration is a real duration, not a number of minutes
+	manager = newNetworkManager(client, 50*time.Millisecond)
+	start = time.Now()
+	_, err = manager.Create(context.Background(), NetworkOptions{Name: "busy"})
+	require.Error(t, err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestNetworkManagerByID(t *testing.T) {
+	ctx := context.Background()
+	client := newFakeNetworkClient()
+	manager := newNetworkManager(client, time.Second)
+
+	id, err := manager.Create(ctx, NetworkOptions{Name: "by-id"})
+	require.NoError(t, err)
+	require.NoError(t, manager.Connect(ctx, id, "mongo", "db"))
+	require.Equal(t, []string{"db"}, client.members["by-id"]["mongo"])
+
+	// the last detach removes the network whether it is named by ID or name
+	require.NoError(t, manager.Disconnect(ctx, id, "mongo"))
+	require.NotContains(t, client.members, "by-id")
+	require.NoError(t, manager.Close(ctx))
+}
+
+func TestNetworkManagerCreateStopsOnPermanentErrors(t *testing.T) {
+	for _, createErr := range []error{
+		errdefs.Conflict(errors.New("network with name taken already exists")),
+		errdefs.InvalidParameter(errors.New("invalid subnet")),
+		errdefs.NotFound(errors.New("plugin not found")),
+	} {
+		client := newFakeNetworkClient()
+		client.createErr = createErr
+		manager := newNetworkManager(client, time.Hour)
+
+		start := time.Now()
+		_, err := manager.Create(context.Background(), NetworkOptions{Name: "taken"})
+		require.ErrorContains(t, err, createErr.Error())
+		require.Equal(t, 1, client.creates)
+		require.Less(t, time.Since(start), time.Second)
+	}
+
+	err := permanentNetworkError(fmt.Errorf("failed to create network: %w", errdefs.Conflict(errors.New("taken"))))
+	var permanent *backoff.PermanentError
+	require.ErrorAs(t, err, &permanent)
+}
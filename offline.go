@@ -0,0 +1,191 @@
+package testcontainers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	dockerclient "github.com/docker/docker/client"
+)
+
+const (
+	// EnvOffline enables the offline mode when set to a true value, see Offline
+	EnvOffline = "TESTCONTAINERS_OFFLINE"
+	// EnvImageArchives is a directory of image tarballs created by docker save,
+	// they are loaded when an image is missing in offline mode
+	EnvImageArchives = "TESTCONTAINERS_IMAGE_ARCHIVES"
+)
+
+// Offline reports whether images must not be pulled, see EnvOffline
+func Offline() (bool, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return false, err
+	}
+	return config.Offline, nil
+}
+
+// MissingImagesError lists the images that are not available locally
+type MissingImagesError struct {
+	Images []string
+}
+
+// Error implements error
+func (e *MissingImagesError) Error() string {
+	return fmt.Sprintf("offline mode: %d images are missing locally: %s (pull them or put their docker save tarballs in $%s)",
+		len(e.Images), strings.Join(e.Images, ", "), EnvImageArchives)
+}
+
+// imageClient is the part of the docker client used by the preflight
+type imageClient interface {
+	ImageInspectWithRaw(ctx context.Context, imageID string) (types.ImageInspect, []byte, error)
+	ImageLoad(ctx context.Context, input io.Reader, quiet bool) (types.ImageLoadResponse, error)
+}
+
+// PreflightImages checks that every image exists locally. When images are missing,
+// the tarballs in archiveDir (if any) are loaded first. The images still missing
+// are reported at once as a *MissingImagesError.
+func PreflightImages(ctx context.Context, images []string, archiveDir string) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return preflightImages(ctx, client, images, archiveDir)
+}
+
+// PreflightModules runs PreflightImages for the default images of modules and the
+// reaper image when offline mode is enabled, it does nothing otherwise
+func PreflightModules(ctx context.Context, modules ...string) error {
+	config, err := LoadConfig()
+	if err != nil || !config.Offline {
+		return err
+	}
+	images, err := requiredImages(modules...)
+	if err != nil {
+		return err
+	}
+	return PreflightImages(ctx, images, config.ImageArchives)
+}
+
+// requiredImages returns the images of modules and, unless it is disabled, of the reaper
+// that testcontainers-go starts with the first container
+func requiredImages(modules ...string) ([]string, error) {
+	images := make([]string, 0, len(modules)+1)
+	for _, module := range modules {
+		image, err := Image(module, "")
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, image)
+	}
+	reaper, enabled, err := ReaperImage()
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		images = append(images, reaper)
+	}
+	return images, nil
+}
+
+func preflightImages(ctx context.Context, client imageClient, images []string, archiveDir string) error {
+	missing, err := missingImages(ctx, client, images)
+	if err != nil || len(missing) == 0 {
+		return err
+	}
+	if archiveDir != "" {
+		if err := loadImages(ctx, client, archiveDir); err != nil {
+			return err
+		}
+		if missing, err = missingImages(ctx, client, missing); err != nil || len(missing) == 0 {
+			return err
+		}
+	}
+	return &MissingImagesError{Images: missing}
+}
+
+func missingImages(ctx context.Context, client imageClient, images []string) ([]string, error) {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, image := range images {
+		if seen[image] {
+			continue
+		}
+		seen[image] = true
+		if _, _, err := client.ImageInspectWithRaw(ctx, image); err != nil {
+			if !dockerclient.IsErrNotFound(err) {
+				return nil, fmt.Errorf("failed to inspect image %s: %v", image, err)
+			}
+			missing = append(missing, image)
+		}
+	}
+	sort.Strings(missing)
+	return missing, nil
+}
+
+// LoadImages loads every docker save tarball (.tar, .tar.gz, .tgz) in dir
+func LoadImages(ctx context.Context, dir string) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return loadImages(ctx, client, dir)
+}
+
+func loadImages(ctx context.Context, client imageClient, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read image archives: %v", err)
+	}
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, ".tar") || strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")) {
+			continue
+		}
+		if err := loadImage(ctx, client, filepath.Join(dir, name)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func loadImage(ctx context.Context, client imageClient, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open image archive: %v", err)
+	}
+	defer f.Close()
+
+	// the daemon detects the compression of the archive
+	resp, err := client.ImageLoad(ctx, f, true)
+	if err != nil {
+		return fmt.Errorf("failed to load image archive %s: %v", path, err)
+	}
+	defer resp.Body.Close()
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&msg); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("failed to load image archive %s: %v", path, err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("failed to load image archive %s: %s", path, msg.Error)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/errdefs"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// fakeImageClient has the images in local, loading an archive adds the images in its content
+type fakeImageClient struct {
+	local  map[string]bool
+	loaded []string
+}
+
+func (c *fakeImageClient) ImageInspectWithRaw(_ context.Context, image string) (types.ImageInspect, []byte, error) {
+	if !c.local[image] {
+		return types.ImageInspect{}, nil, errdefs.NotFound(errors.New("No such image: " + image))
+	}
+	return types.ImageInspect{ID: image}, nil, nil
+}
+
+func (c *fakeImageClient) ImageLoad(_ context.Context, input io.Reader, _ bool) (types.ImageLoadResponse, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return types.ImageLoadResponse{}, err
+	}
+	if strings.HasPrefix(string(data), "corrupt") {
+		body := `{"stream":"Loading layer"}` + "\n" + `{"errorDetail":{"message":"unexpected EOF"},"error":"unexpected EOF"}`
+		return types.ImageLoadResponse{Body: io.NopCloser(strings.NewReader(body)), JSON: true}, nil
+	}
+	for _, image := range strings.Fields(string(data)) {
+		c.local[image] = true
+		c.loaded = append(c.loaded, image)
+	}
+	body := `{"stream":"Loaded image: ` + string(data) + `"}`
+	return types.ImageLoadResponse{Body: io.NopCloser(strings.NewReader(body)), JSON: true}, nil
+}
+
+func TestRequiredImages(t *testing.T) {
+	isolateConfig(t)
+	t.Setenv("TESTCONTAINERS_RYUK_DISABLED", "")
+	images, err := requiredImages("redis")
+	require.NoError(t, err)
+	require.Equal(t, []string{"redis:7.0.5", testcontainers.ReaperDefaultImage}, images)
+
+	t.Setenv("TESTCONTAINERS_RYUK_DISABLED", "true")
+	images, err = requiredImages("redis")
+	require.NoError(t, err)
+	require.Equal(t, []string{"redis:7.0.5"}, images)
+}
+
+func TestOfflineReportsConfigErrors(t *testing.T) {
+	isolateConfig(t)
+	writeConfig(t, "offline: [\n")
+	_, err := Offline()
+	require.Error(t, err)
+}
+
+func TestPreflightImages(t *testing.T) {
+	ctx := context.Background()
+	client := &fakeImageClient{local: map[string]bool{"redis:7.0.5": true}}
+
+	err := preflightImages(ctx, client, []string{"redis:7.0.5", "mongo:6.0.2", "bitnami/zookeeper:3.8.1", "mongo:6.0.2"}, "")
+	var missing *MissingImagesError
+	require.True(t, errors.As(err, &missing))
+	require.Equal(t, []string{"bitnami/zookeeper:3.8.1", "mongo:6.0.2"}, missing.Images)
+	require.Contains(t, err.Error(), "2 images are missing locally: bitnami/zookeeper:3.8.1, mongo:6.0.2")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "mongo.tar"), []byte("mongo:6.0.2"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README"), []byte("bitnami/zookeeper:3.8.1"), 0o644))
+	err = preflightImages(ctx, client, []string{"redis:7.0.5", "mongo:6.0.2", "bitnami/zookeeper:3.8.1"}, dir)
+	require.True(t, errors.As(err, &missing))
+	require.Equal(t, []string{"bitnami/zookeeper:3.8.1"}, missing.Images)
+	require.Equal(t, []string{"mongo:6.0.2"}, client.loaded)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "zookeeper.tar.gz"), []byte("bitnami/zookeeper:3.8.1"), 0o644))
+	require.NoError(t, preflightImages(ctx, client, []string{"mongo:6.0.2", "bitnami/zookeeper:3.8.1"}, dir))
+}
+
+func TestLoadImagesReportsErrors(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.tar"), []byte("corrupt"), 0o644))
+	err := loadImages(context.Background(), &fakeImageClient{local: map[string]bool{}}, dir)
+	require.ErrorContains(t, err, "broken.tar: unexpected EOF")
+
+	err = loadImages(context.Background(), &fakeImageClient{}, filepath.Join(dir, "missing"))
+	require.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package testcontainers
 
 import (
+	"testing"
 	"time"
 
 	"github.com/testcontainers/testcontainers-go"
@@ -10,8 +11,21 @@ import (
 type ContainerOptions struct {
 	testcontainers.ContainerRequest
 	StartupTimeout time.Duration
-}
+	// TestName is stamped on the container as the creating test, see SessionLabels
+	TestName string
+	// TB names the containers and networks of the module after the test, see ResourceName,
+	// and is the creating test when TestName is empty
+	TB testing.TB
+	// MergePolicy controls how ContainerRequest is merged with the module defaults
+	MergePolicy MergePolicy
 
-// ContainerConfig ...
-type ContainerConfig struct {
+	// Memory limits the container memory in bytes, 0 is unlimited
+	Memory int64
+	// CPUs limits the container to a number of CPUs, e.g. 0.5, 0 is unlimited
+	CPUs float64
+	// PidsLimit limits the number of processes in the container, 0 is unlimited
+	PidsLimit int64
+	// InMemoryStorage mounts a tmpfs at the data directory of the module, more tmpfs
+	// mounts can be set in ContainerRequest.Tmpfs
+	InMemoryStorage bool
 }
@@ -0,0 +1,86 @@
+// Package oteltrace records module starts as OpenTelemetry spans, see Hook.
+package oteltrace
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	tc "github.com/mmadfox/testcontainers"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes set on the spans
+const (
+	AttributeModule    = attribute.Key("testcontainers.module")
+	AttributePhase     = attribute.Key("testcontainers.phase")
+	AttributeStartupID = attribute.Key("testcontainers.startup_id")
+)
+
+type hook struct {
+	ctx    context.Context
+	tracer trace.Tracer
+
+	mu sync.Mutex
+	// starts holds the spans of the running starts by startup id
+	starts map[uint64]start
+}
+
+type start struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// Hook returns a testcontainers.StartupHook recording every module start as a span named after
+// the module, a child of the span in ctx if any, with a child span per phase.
+// The span of a start ends with Startup.Done or Startup.Fail.
+func Hook(ctx context.Context, tracer trace.Tracer) tc.StartupHook {
+	h := &hook{ctx: ctx, tracer: tracer, starts: make(map[uint64]start)}
+	return h.record
+}
+
+func (h *hook) record(event tc.PhaseEvent) {
+	h.mu.Lock()
+	s, ok := h.starts[event.StartupID]
+	if !ok {
+		s.ctx, s.span = h.tracer.Start(h.ctx, event.Module,
+			trace.WithTimestamp(event.Phase.Start),
+			trace.WithAttributes(
+				AttributeModule.String(event.Module),
+				AttributeStartupID.Int64(int64(event.StartupID)),
+			))
+		h.starts[event.StartupID] = s
+	}
+	if event.Last {
+		delete(h.starts, event.StartupID)
+	}
+	h.mu.Unlock()
+
+	end := event.Phase.Start.Add(event.Phase.Duration)
+	if event.Phase.Name != "" {
+		_, span := h.tracer.Start(s.ctx, event.Phase.Name,
+			trace.WithTimestamp(event.Phase.Start),
+			trace.WithAttributes(
+				AttributeModule.String(event.Module),
+				AttributePhase.String(event.Phase.Name),
+			))
+		setError(span, event.Err)
+		span.End(trace.WithTimestamp(end))
+	}
+	if event.Last {
+		setError(s.span, event.Err)
+		s.span.End(trace.WithTimestamp(end))
+	}
+}
+
+func setError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	// StartError messages continue with the state and logs
+	description, _, _ := strings.Cut(err.Error(), "\n")
+	span.SetStatus(codes.Error, description)
+}
@@ -0,0 +1,65 @@
+package oteltrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tc "github.com/mmadfox/testcontainers"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := provider.Tracer("test")
+
+	ctx, parent := tracer.Start(context.Background(), "TestHook")
+	remove := tc.AddStartupHook(Hook(ctx, tracer))
+	defer remove()
+
+	kafka := tc.NewStartup("kafka")
+	kafka.Phase(tc.PhasePull)
+	kafka.Phase(tc.PhaseWait)
+	kafka.Phase("")
+	kafka.Phase("kafka version")
+	kafka.Done()
+
+	mongo := tc.NewStartup("mongo")
+	mongo.Phase(tc.PhaseWait)
+	_ = mongo.Fail(nil, errors.New("wait timed out\nlogs"))
+	remove()
+	parent.End()
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	var names []string
+	for _, span := range recorder.Ended() {
+		name := span.Name()
+		if span.Parent().SpanID() == parent.SpanContext().SpanID() {
+			name = "/" + name
+		}
+		spans[name] = span
+		names = append(names, name)
+	}
+	require.ElementsMatch(t, []string{
+		"TestHook",
+		"/kafka", "pull", "wait", "kafka version",
+		"/mongo", "wait",
+	}, names)
+
+	kafkaSpan := spans["/kafka"]
+	for _, name := range []string{"pull", "kafka version"} {
+		span := spans[name]
+		require.Equal(t, kafkaSpan.SpanContext().SpanID(), span.Parent().SpanID())
+		require.False(t, span.StartTime().Before(kafkaSpan.StartTime()))
+		require.False(t, span.EndTime().After(kafkaSpan.EndTime()))
+	}
+	require.Equal(t, codes.Unset, kafkaSpan.Status().Code)
+
+	mongoSpan := spans["/mongo"]
+	require.Equal(t, codes.Error, mongoSpan.Status().Code)
+	require.Equal(t, "wait timed out", mongoSpan.Status().Description)
+}
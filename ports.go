@@ -0,0 +1,32 @@
+package testcontainers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+)
+
+// portConflictAttempts is the number of times StartContainer tries to start a container
+// whose host port is already allocated
+const portConflictAttempts = 3
+
+// ExposedPort returns the ExposedPorts entry publishing containerPort on hostPort.
+// A hostPort <= 0 lets docker pick a free ephemeral port, which is the safe default
+// when several test binaries run in parallel.
+func ExposedPort(hostPort int, containerPort nat.Port) string {
+	if hostPort <= 0 {
+		return string(containerPort)
+	}
+	return fmt.Sprintf("%d:%s", hostPort, containerPort)
+}
+
+// IsPortConflict reports whether err was caused by a host port that is already in use
+func IsPortConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "port is already allocated") ||
+		strings.Contains(msg, "address already in use")
+}
@@ -0,0 +1,21 @@
+package testcontainers
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExposedPort(t *testing.T) {
+	require.Equal(t, "27017/tcp", ExposedPort(0, "27017/tcp"))
+	require.Equal(t, "6379/tcp", ExposedPort(-1, "6379/tcp"))
+	require.Equal(t, "2189:27017/tcp", ExposedPort(2189, "27017/tcp"))
+}
+
+func TestIsPortConflict(t *testing.T) {
+	require.True(t, IsPortConflict(errors.New(`Error response from daemon: driver failed programming external connectivity on endpoint test-redis: Bind for 0.0.0.0:3890 failed: port is already allocated`)))
+	require.True(t, IsPortConflict(errors.New(`listen tcp4 0.0.0.0:2189: bind: address already in use`)))
+	require.False(t, IsPortConflict(errors.New("No such image: mongo:latest")))
+	require.False(t, IsPortConflict(nil))
+}
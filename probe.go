@@ -0,0 +1,99 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+const (
+	defaultProbeTimeout      = time.Minute
+	defaultProbePollInterval = 250 * time.Millisecond
+	// probeAttemptTimeout bounds a single probe so a hanging handshake is retried
+	probeAttemptTimeout = 5 * time.Second
+)
+
+// Probe checks that a started container serves its protocol,
+// an error means that it is not ready yet
+type Probe func(ctx context.Context, target wait.StrategyTarget) error
+
+// ProbeStrategy is a wait.Strategy running a Probe until it succeeds
+type ProbeStrategy struct {
+	probe        Probe
+	timeout      *time.Duration
+	PollInterval time.Duration
+}
+
+// ForProbe returns a wait strategy for probe
+func ForProbe(probe Probe) *ProbeStrategy {
+	return &ProbeStrategy{probe: probe, PollInterval: defaultProbePollInterval}
+}
+
+// WithStartupTimeout changes the default timeout of one minute
+func (s *ProbeStrategy) WithStartupTimeout(timeout time.Duration) *ProbeStrategy {
+	s.timeout = &timeout
+	return s
+}
+
+// WithPollInterval changes the default interval of 250ms between probes
+func (s *ProbeStrategy) WithPollInterval(interval time.Duration) *ProbeStrategy {
+	s.PollInterval = interval
+	return s
+}
+
+// Timeout implements wait.StrategyTimeout
+func (s *ProbeStrategy) Timeout() *time.Duration {
+	return s.timeout
+}
+
+// WaitUntilReady runs the probe until it succeeds, the container exits or the timeout expires
+func (s *ProbeStrategy) WaitUntilReady(ctx context.Context, target wait.StrategyTarget) error {
+	timeout := defaultProbeTimeout
+	if s.timeout != nil {
+		timeout = *s.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		err := s.attempt(ctx, target)
+		if err == nil {
+			return nil
+		}
+		if state, stateErr := target.State(ctx); stateErr == nil && !state.Running {
+			return fmt.Errorf("container is %s (exit code %d) while probing readiness: %v", state.Status, state.ExitCode, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: readiness probe did not succeed: %v", ctx.Err(), err)
+		case <-time.After(s.PollInterval):
+		}
+	}
+}
+
+func (s *ProbeStrategy) attempt(ctx context.Context, target wait.StrategyTarget) error {
+	ctx, cancel := context.WithTimeout(ctx, probeAttemptTimeout)
+	defer cancel()
+	return s.probe(ctx, target)
+}
+
+// ProbeEndpoint returns the host:port the container port is reachable at
+func ProbeEndpoint(ctx context.Context, target wait.StrategyTarget, port nat.Port) (string, error) {
+	host, err := ContainerHost(ctx, target)
+	if err != nil {
+		return "", fmt.Errorf("failed to get container host: %v", err)
+	}
+	mapped, err := target.MappedPort(ctx, port)
+	if err != nil {
+		return "", fmt.Errorf("failed to get exposed container port: %v", err)
+	}
+	if mapped.Port() == "" {
+		return "", errors.New("container port is not mapped yet")
+	}
+	return net.JoinHostPort(host, mapped.Port()), nil
+}
@@ -0,0 +1,68 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// probedContainer is a running container whose port is mapped to 49153
+type probedContainer struct {
+	testcontainers.Container
+	state types.ContainerState
+}
+
+func (c *probedContainer) Host(context.Context) (string, error) {
+	return "localhost", nil
+}
+
+func (c *probedContainer) MappedPort(_ context.Context, port nat.Port) (nat.Port, error) {
+	return nat.NewPort(port.Proto(), "49153")
+}
+
+func (c *probedContainer) State(context.Context) (*types.ContainerState, error) {
+	return &c.state, nil
+}
+
+func TestProbeStrategy(t *testing.T) {
+	c := &probedContainer{state: types.ContainerState{Status: "running", Running: true}}
+	attempts := 0
+	strategy := ForProbe(func(ctx context.Context, target wait.StrategyTarget) error {
+		addr, err := ProbeEndpoint(ctx, target, "6379/tcp")
+		require.NoError(t, err)
+		require.Equal(t, "localhost:49153", addr)
+		if attempts++; attempts < 3 {
+			return errors.New("LOADING Redis is loading the dataset in memory")
+		}
+		return nil
+	}).WithPollInterval(time.Millisecond)
+	require.NoError(t, strategy.WaitUntilReady(context.Background(), c))
+	require.Equal(t, 3, attempts)
+
+	// a probe that never succeeds times out and keeps its last error
+	strategy = ForProbe(func(context.Context, wait.StrategyTarget) error {
+		return errors.New("connection refused")
+	}).WithStartupTimeout(20 * time.Millisecond).WithPollInterval(time.Millisecond)
+	err := strategy.WaitUntilReady(context.Background(), c)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorContains(t, err, "connection refused")
+	require.ErrorIs(t, &StartError{Phase: PhaseWait, Err: err}, ErrStartupTimeout)
+}
+
+func TestProbeStrategyExitedContainer(t *testing.T) {
+	c := &probedContainer{state: types.ContainerState{Status: "exited", ExitCode: 1}}
+	strategy := ForProbe(func(context.Context, wait.StrategyTarget) error {
+		return errors.New("connection refused")
+	})
+	start := time.Now()
+	err := strategy.WaitUntilReady(context.Background(), c)
+	require.ErrorContains(t, err, "container is exited (exit code 1)")
+	require.Less(t, time.Since(start), time.Second)
+}
@@ -3,18 +3,25 @@ package rabbitmq
 import (
 	"context"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/docker/go-connections/nat"
 	tc "github.com/mmadfox/testcontainers"
+	"github.com/streadway/amqp"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// DataPath is the data directory of rabbitmq, put on tmpfs by InMemoryStorage
+const DataPath = "/var/lib/rabbitmq"
+
 // Options ...
 type Options struct {
 	tc.ContainerOptions
 	ImageTag string
+	// ReadinessProbe replaces HandshakeProbe("guest", "guest") as the check that rabbitmq is ready
+	ReadinessProbe tc.Probe
 }
 
 // Container ...
@@ -26,15 +33,22 @@ type Container struct {
 }
 
 // Terminate ...
-func (c *Container) Terminate(ctx context.Context) {
-	if c.Container != nil {
-		c.Container.Terminate(ctx)
+func (c *Container) Terminate(ctx context.Context) error {
+	if c.Container == nil {
+		return nil
+	}
+	if err := c.Container.Terminate(ctx); err != nil {
+		return fmt.Errorf("failed to terminate rabbitmq container %s: %w", c.Container.GetContainerID(), err)
 	}
+	return nil
 }
 
 // Start ...
 func Start(ctx context.Context, options Options) (Container, error) {
 	var container Container
+	if _, err := tc.ApplyDefaults("rabbitmq", &options.ContainerOptions); err != nil {
+		return container, err
+	}
 	port, err := nat.NewPort("", "5672")
 	if err != nil {
 		return container, fmt.Errorf("failed to build port: %v", err)
@@ -45,40 +59,86 @@ func Start(ctx context.Context, options Options) (Container, error) {
 		timeout = 5 * time.Minute // Default timeout
 	}
 
-	tag := "latest"
-	if options.ImageTag != "" {
-		tag = options.ImageTag
+	image, err := tc.Image("rabbitmq", options.ImageTag)
+	if err != nil {
+		return container, err
 	}
 
 	req := testcontainers.ContainerRequest{
-		Image:        fmt.Sprintf("rabbitmq:%s", tag),
+		Image:        image,
 		ExposedPorts: []string{string(port)},
-		WaitingFor:   wait.ForListeningPort(port).WithStartupTimeout(timeout),
-		// WaitingFor:   wait.ForLog("Server startup complete").WithStartupTimeout(timeout),
 	}
+	probe := options.ReadinessProbe
+	if probe == nil {
+		probe = HandshakeProbe("guest", "guest")
+	}
+	req.WaitingFor = tc.ForProbe(probe).WithStartupTimeout(timeout)
 
-	tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest)
+	if err := tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest, options.MergePolicy); err != nil {
+		return container, err
+	}
+	tc.WithTestNaming(&req, options.ContainerOptions, "rabbitmq")
+	if err := tc.WithResources(&req, options.ContainerOptions, DataPath); err != nil {
+		return container, err
+	}
 
-	rmqContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
+	startup := tc.NewStartup("rabbitmq")
+	rmqContainer, err := startup.StartContainer(ctx, req)
 	if err != nil {
-		return container, fmt.Errorf("failed to start container: %v", err)
+		return container, err
 	}
+	startup.Phase(tc.PhaseInspect)
 	container.Container = rmqContainer
 
-	host, err := rmqContainer.Host(ctx)
+	host, err := tc.ContainerHost(ctx, rmqContainer)
 	if err != nil {
-		return container, fmt.Errorf("failed to get container host: %v", err)
+		return container, startup.Fail(rmqContainer, fmt.Errorf("failed to get container host: %v", err))
 	}
 	container.Host = host
 
 	realPort, err := rmqContainer.MappedPort(ctx, port)
 	if err != nil {
-		return container, fmt.Errorf("failed to get exposed container port: %v", err)
+		return container, startup.Fail(rmqContainer, fmt.Errorf("failed to get exposed container port: %v", err))
 	}
 	container.Port = int64(realPort.Int())
 
+	container.ContainerConfig, err = tc.InspectContainer(ctx, rmqContainer)
+	if err != nil {
+		return container, startup.Fail(rmqContainer, err)
+	}
+	startup.Done()
+
 	return container, nil
 }
+
+// HandshakeProbe is ready when rabbitmq completes an AMQP handshake and opens a channel,
+// which fails while the broker is still booting
+func HandshakeProbe(user, password string) tc.Probe {
+	return func(ctx context.Context, target wait.StrategyTarget) error {
+		addr, err := tc.ProbeEndpoint(ctx, target, "5672/tcp")
+		if err != nil {
+			return err
+		}
+		uri := url.URL{Scheme: "amqp", User: url.UserPassword(user, password), Host: addr, Path: "/"}
+		conn, err := amqp.DialConfig(uri.String(), amqp.Config{
+			Dial: amqp.DefaultDial(2 * time.Second),
+		})
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		ch, err := conn.Channel()
+		if err != nil {
+			return err
+		}
+		return ch.Close()
+	}
+}
+
+// NewModule returns a tc.Module starting a container with options
+func NewModule(options Options) *tc.ModuleOf[*Container] {
+	return tc.NewModule(func(ctx context.Context) (*Container, error) {
+		container, err := Start(ctx, options)
+		return &container, err
+	})
+}
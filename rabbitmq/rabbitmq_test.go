@@ -25,12 +25,11 @@ func TestRabbitmq(t *testing.T) {
 	defer container.Terminate(ctx)
 
 	// start logger
-	logger, err := tc.StartLogger(ctx, container.Container)
+	logger, err := tc.StartLogger(ctx, container.Container, tc.WithLogSink(tc.TestingSink(t)))
 	if err != nil {
 		t.Errorf("failed to start logger: %v", err)
 	} else {
 		defer logger.Stop()
-		go logger.LogToStdout()
 	}
 
 	exchangeName := "exchange_name"
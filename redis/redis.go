@@ -3,24 +3,27 @@ package redis
 import (
 	"context"
 	"fmt"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/testcontainers/testcontainers-go"
 
-	"github.com/docker/go-connections/nat"
+	goredis "github.com/go-redis/redis"
 	tc "github.com/mmadfox/testcontainers"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// DataPath is the data directory of redis, put on tmpfs by InMemoryStorage
+const DataPath = "/data"
+
 // Options ...
 type Options struct {
 	tc.ContainerOptions
 
-	Port     int
+	Port     int // pinned host port, 0 lets docker pick a free one, see Container.Port
 	Password string
 	ImageTag string
+	// ReadinessProbe replaces PingProbe as the check that redis is ready
+	ReadinessProbe tc.Probe
 }
 
 // Container ...
@@ -33,10 +36,14 @@ type Container struct {
 }
 
 // Terminate ...
-func (c *Container) Terminate(ctx context.Context) {
-	if c.Container != nil {
-		_ = c.Container.Terminate(ctx)
+func (c *Container) Terminate(ctx context.Context) error {
+	if c.Container == nil {
+		return nil
+	}
+	if err := c.Container.Terminate(ctx); err != nil {
+		return fmt.Errorf("failed to terminate redis container %s: %w", c.Container.GetContainerID(), err)
 	}
+	return nil
 }
 
 // ConnectionURI ...
@@ -47,61 +54,96 @@ func (c *Container) ConnectionURI() string {
 // Start ...
 func Start(ctx context.Context, options Options) (Container, error) {
 	var container Container
-	if options.Port <= 0 {
-		options.Port = 6379
+	if _, err := tc.ApplyDefaults("redis", &options.ContainerOptions); err != nil {
+		return container, err
 	}
-	port, err := nat.NewPort("", strconv.Itoa(options.Port))
-	if err != nil {
-		return container, fmt.Errorf("failed to build port: %v", err)
-	}
-
 	timeout := options.ContainerOptions.StartupTimeout
 	if int64(timeout) < 1 {
 		timeout = time.Minute // Default timeout
 	}
-	rawPort := strings.Trim(string(port), "/")
 
-	tag := "latest"
-	if options.ImageTag != "" {
-		tag = options.ImageTag
+	image, err := tc.Image("redis", options.ImageTag)
+	if err != nil {
+		return container, err
 	}
 	exposedPorts := []string{
-		fmt.Sprintf("%s:%s", rawPort, "6379"),
+		tc.ExposedPort(options.Port, "6379/tcp"),
 	}
 	req := testcontainers.ContainerRequest{
-		Image:        fmt.Sprintf("redis:%s", tag),
+		Image:        image,
 		ExposedPorts: exposedPorts,
-		WaitingFor:   wait.ForListeningPort("6379").WithStartupTimeout(timeout),
 	}
+	probe := options.ReadinessProbe
+	if probe == nil {
+		probe = PingProbe(options.Password)
+	}
+	req.WaitingFor = tc.ForProbe(probe).WithStartupTimeout(timeout)
 
 	if options.Password != "" {
 		req.Cmd = []string{fmt.Sprintf("redis-server --requirepass %s", options.Password)}
 		container.Password = options.Password
 	}
 
-	tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest)
+	if err := tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest, options.MergePolicy); err != nil {
+		return container, err
+	}
+	tc.WithTestNaming(&req, options.ContainerOptions, "redis")
+	if err := tc.WithResources(&req, options.ContainerOptions, DataPath); err != nil {
+		return container, err
+	}
 
-	redisContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
+	startup := tc.NewStartup("redis")
+	redisContainer, err := startup.StartContainer(ctx, req)
 	container.Container = redisContainer
 
 	if err != nil {
-		return container, fmt.Errorf("failed to start container: %v", err)
+		return container, err
 	}
+	startup.Phase(tc.PhaseInspect)
 
-	host, err := redisContainer.Host(ctx)
+	host, err := tc.ContainerHost(ctx, redisContainer)
 	if err != nil {
-		return container, fmt.Errorf("failed to get container host: %v", err)
+		return container, startup.Fail(redisContainer, fmt.Errorf("failed to get container host: %v", err))
 	}
 	container.Host = host
 
 	realPort, err := redisContainer.MappedPort(ctx, "6379")
 	if err != nil {
-		return container, fmt.Errorf("failed to get exposed container port: %v", err)
+		return container, startup.Fail(redisContainer, fmt.Errorf("failed to get exposed container port: %v", err))
 	}
 	container.Port = int64(realPort.Int())
 
+	container.ContainerConfig, err = tc.InspectContainer(ctx, redisContainer)
+	if err != nil {
+		return container, startup.Fail(redisContainer, err)
+	}
+	startup.Done()
+
 	return container, nil
 }
+
+// PingProbe is ready when redis answers PING
+func PingProbe(password string) tc.Probe {
+	return func(ctx context.Context, target wait.StrategyTarget) error {
+		addr, err := tc.ProbeEndpoint(ctx, target, "6379/tcp")
+		if err != nil {
+			return err
+		}
+		client := goredis.NewClient(&goredis.Options{
+			Addr:        addr,
+			Password:    password,
+			DialTimeout: 2 * time.Second,
+			ReadTimeout: 2 * time.Second,
+		})
+		defer client.Close()
+		return client.Ping().Err()
+	}
+}
+
+// NewModule returns a tc.Module starting a container with options
+func NewModule(options Options) *tc.ModuleOf[*Container] {
+	return tc.NewModule(func(ctx context.Context) (*Container, error) {
+		container, err := Start(ctx, options)
+		return &container, err
+	})
+}
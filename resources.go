@@ -0,0 +1,59 @@
+package testcontainers
+
+import (
+	"errors"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+// WithResources applies the memory, CPU and pids limits of options to a container request
+// and puts dataPath, the data directory of the module, on tmpfs when InMemoryStorage is set.
+// Tmpfs mounts set by the caller, including one at dataPath, are kept.
+func WithResources(req *testcontainers.ContainerRequest, options ContainerOptions, dataPath string) error {
+	if options.Memory < 0 || options.CPUs < 0 || options.PidsLimit < 0 {
+		return errors.New("failed to apply resources: limits must not be negative")
+	}
+
+	var limits container.Resources
+	if options.Memory > 0 {
+		limits.Memory = options.Memory
+	}
+	if options.CPUs > 0 {
+		limits.NanoCPUs = int64(options.CPUs * 1e9)
+	}
+	if options.PidsLimit > 0 {
+		pids := options.PidsLimit
+		limits.PidsLimit = &pids
+	}
+	applyLimits(&req.Resources, limits)
+	// a custom HostConfigModifier replaces the one copying req.Resources
+	if modifier := req.HostConfigModifier; modifier != nil {
+		req.HostConfigModifier = func(hostConfig *container.HostConfig) {
+			modifier(hostConfig)
+			applyLimits(&hostConfig.Resources, limits)
+		}
+	}
+
+	if options.InMemoryStorage && dataPath != "" {
+		if req.Tmpfs == nil {
+			req.Tmpfs = make(map[string]string)
+		}
+		if _, ok := req.Tmpfs[dataPath]; !ok {
+			req.Tmpfs[dataPath] = "rw"
+		}
+	}
+	return nil
+}
+
+func applyLimits(resources *container.Resources, limits container.Resources) {
+	if limits.Memory > 0 {
+		resources.Memory = limits.Memory
+	}
+	if limits.NanoCPUs > 0 {
+		resources.NanoCPUs = limits.NanoCPUs
+	}
+	if limits.PidsLimit != nil {
+		resources.PidsLimit = limits.PidsLimit
+	}
+}
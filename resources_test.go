@@ -0,0 +1,48 @@
+package testcontainers
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestWithResources(t *testing.T) {
+	req := testcontainers.ContainerRequest{Image: "mongo"}
+	options := ContainerOptions{
+		Memory:          512 << 20,
+		CPUs:            1.5,
+		PidsLimit:       256,
+		InMemoryStorage: true,
+	}
+	require.NoError(t, WithResources(&req, options, "/data/db"))
+	require.Equal(t, int64(512<<20), req.Resources.Memory)
+	require.Equal(t, int64(1500000000), req.Resources.NanoCPUs)
+	require.Equal(t, int64(256), *req.Resources.PidsLimit)
+	require.Equal(t, map[string]string{"/data/db": "rw"}, req.Tmpfs)
+
+	// the caller's tmpfs options win and no limits are set by default
+	req = testcontainers.ContainerRequest{Tmpfs: map[string]string{"/data/db": "rw,size=64m"}}
+	require.NoError(t, WithResources(&req, ContainerOptions{InMemoryStorage: true}, "/data/db"))
+	require.Equal(t, "rw,size=64m", req.Tmpfs["/data/db"])
+	require.Zero(t, req.Resources.Memory)
+	require.Nil(t, req.Resources.PidsLimit)
+
+	require.Error(t, WithResources(&req, ContainerOptions{CPUs: -1}, ""))
+}
+
+func TestWithResourcesHostConfigModifier(t *testing.T) {
+	req := testcontainers.ContainerRequest{
+		HostConfigModifier: func(hostConfig *container.HostConfig) {
+			hostConfig.Privileged = true
+		},
+	}
+	require.NoError(t, WithResources(&req, ContainerOptions{Memory: 64 << 20}, "/data"))
+	require.Nil(t, req.Tmpfs)
+
+	var hostConfig container.HostConfig
+	req.HostConfigModifier(&hostConfig)
+	require.True(t, hostConfig.Privileged)
+	require.Equal(t, int64(64<<20), hostConfig.Memory)
+}
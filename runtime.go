@@ -0,0 +1,212 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Container runtimes told apart by DetectRuntime
+const (
+	RuntimeDocker         = "docker"
+	RuntimeDockerRootless = "docker-rootless"
+	RuntimePodman         = "podman"
+)
+
+// EnvHostOverride replaces the address published ports are reached at, e.g. when
+// the daemon is reached through a tunnel
+const EnvHostOverride = "TESTCONTAINERS_HOST_OVERRIDE"
+
+// ErrNoRuntime is returned when neither DOCKER_HOST nor a known socket is found
+var ErrNoRuntime = errors.New("no container runtime found, set DOCKER_HOST")
+
+const envRyukPrivileged = "TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED"
+
+// Runtime is the container engine the library talks to
+type Runtime struct {
+	Kind string
+	// Host is the daemon address, e.g. unix:///run/user/1000/docker.sock or tcp://10.0.0.2:2376
+	Host string
+	// Source tells where Host was found, DOCKER_HOST or the probed socket
+	Source string
+	// ReaperPrivileged is true on Podman unless TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED is set
+	ReaperPrivileged bool
+}
+
+// DetectRuntime finds the daemon from DOCKER_HOST, then the docker socket, the rootless
+// docker socket under XDG_RUNTIME_DIR and the rootless and rootful Podman sockets
+func DetectRuntime() (Runtime, error) {
+	return detectRuntime(os.Getenv, func(path string) bool {
+		info, err := os.Stat(path)
+		return err == nil && info.Mode()&os.ModeSocket != 0
+	})
+}
+
+func detectRuntime(getenv func(string) string, isSocket func(path string) bool) (Runtime, error) {
+	runtime, err := findRuntime(getenv, isSocket)
+	if err != nil {
+		return runtime, err
+	}
+	runtime.ReaperPrivileged = runtime.Kind == RuntimePodman
+	if privileged, err := strconv.ParseBool(getenv(envRyukPrivileged)); err == nil {
+		runtime.ReaperPrivileged = privileged
+	}
+	return runtime, nil
+}
+
+func findRuntime(getenv func(string) string, isSocket func(path string) bool) (Runtime, error) {
+	xdg := getenv("XDG_RUNTIME_DIR")
+	if host := getenv("DOCKER_HOST"); host != "" {
+		// the docker client can only dial ssh through the docker cli
+		if strings.HasPrefix(host, "ssh://") {
+			return Runtime{}, fmt.Errorf("docker host %s is not supported, forward the socket with ssh -L and set DOCKER_HOST to it", host)
+		}
+		return Runtime{Kind: runtimeKind(host, xdg), Host: host, Source: "DOCKER_HOST"}, nil
+	}
+
+	sockets := []string{"/var/run/docker.sock"}
+	if xdg != "" {
+		sockets = append(sockets, filepath.Join(xdg, "docker.sock"), filepath.Join(xdg, "podman", "podman.sock"))
+	}
+	sockets = append(sockets, "/run/podman/podman.sock")
+	for _, socket := range sockets {
+		if isSocket(socket) {
+			host := "unix://" + socket
+			return Runtime{Kind: runtimeKind(host, xdg), Host: host, Source: socket}, nil
+		}
+	}
+	return Runtime{}, ErrNoRuntime
+}
+
+func runtimeKind(host, xdg string) string {
+	switch {
+	case strings.Contains(host, "podman"):
+		return RuntimePodman
+	case !strings.HasPrefix(host, "unix://"):
+		return RuntimeDocker
+	case xdg != "" && strings.HasPrefix(strings.TrimPrefix(host, "unix://"), xdg+"/"),
+		strings.Contains(host, "/run/user/"):
+		return RuntimeDockerRootless
+	}
+	return RuntimeDocker
+}
+
+// ProviderType is the testcontainers-go provider of the runtime
+func (r Runtime) ProviderType() testcontainers.ProviderType {
+	if r.Kind == RuntimePodman {
+		return testcontainers.ProviderPodman
+	}
+	return testcontainers.ProviderDocker
+}
+
+// remoteHost returns the address of a daemon reached over the network,
+// false for local sockets whose ports are published on the local host
+func (r Runtime) remoteHost() (string, bool, error) {
+	u, err := url.Parse(r.Host)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to parse docker host %s: %v", r.Host, err)
+	}
+	switch u.Scheme {
+	case "tcp", "http", "https":
+		host := u.Hostname()
+		// a daemon listening on every interface is reached locally
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "localhost"
+		}
+		return host, true, nil
+	}
+	return "", false, nil
+}
+
+var (
+	runtimeOnce    sync.Once
+	currentRuntime Runtime
+	runtimeErr     error
+)
+
+// CurrentRuntime detects the runtime once per process. The environment is left untouched,
+// the docker clients and providers of this package are pointed at the runtime instead.
+func CurrentRuntime() (Runtime, error) {
+	runtimeOnce.Do(func() {
+		currentRuntime, runtimeErr = DetectRuntime()
+	})
+	return currentRuntime, runtimeErr
+}
+
+// newDockerProvider creates a testcontainers-go provider talking to the current runtime,
+// the default provider of testcontainers-go when none is found
+func newDockerProvider() (*testcontainers.DockerProvider, error) {
+	runtime, err := CurrentRuntime()
+	if errors.Is(err, ErrNoRuntime) {
+		return testcontainers.NewDockerProvider(testcontainers.WithDefaultBridgeNetwork(testcontainers.Bridge))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReaper(runtime, testcontainers.ReadConfig()); err != nil {
+		return nil, err
+	}
+
+	bridge := testcontainers.Bridge
+	if runtime.Kind == RuntimePodman {
+		// the bridge network of Podman is called podman
+		bridge = testcontainers.Podman
+	}
+	provider, err := testcontainers.NewDockerProvider(testcontainers.WithDefaultBridgeNetwork(bridge))
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewDockerClient()
+	if err != nil {
+		return nil, err
+	}
+	provider.SetClient(client)
+	return provider, nil
+}
+
+// checkReaper rejects a runtime the reaper cannot work with, testcontainers-go only
+// configures the socket it mounts and its privileges from the environment
+func checkReaper(runtime Runtime, config testcontainers.TestcontainersConfig) error {
+	if config.RyukDisabled {
+		return nil
+	}
+	if strings.HasPrefix(runtime.Host, "unix://") && runtime.Host != config.Host {
+		return fmt.Errorf("the reaper would mount %s instead of %s, set DOCKER_HOST=%s or TESTCONTAINERS_RYUK_DISABLED=true",
+			config.Host, runtime.Host, runtime.Host)
+	}
+	if runtime.ReaperPrivileged && !config.RyukPrivileged {
+		return fmt.Errorf("the reaper must run privileged on %s, set %s=true or TESTCONTAINERS_RYUK_DISABLED=true",
+			runtime.Kind, envRyukPrivileged)
+	}
+	return nil
+}
+
+// ContainerHost returns the address the published ports of c are reachable at:
+// EnvHostOverride or TC_HOST when set, the host of a daemon reached over TCP,
+// the local host otherwise
+func ContainerHost(ctx context.Context, c wait.StrategyTarget) (string, error) {
+	for _, env := range []string{EnvHostOverride, "TC_HOST"} {
+		if host := os.Getenv(env); host != "" {
+			return host, nil
+		}
+	}
+	if runtime, err := CurrentRuntime(); err == nil {
+		host, remote, err := runtime.remoteHost()
+		if err != nil {
+			return "", err
+		}
+		if remote {
+			return host, nil
+		}
+	}
+	return c.Host(ctx)
+}
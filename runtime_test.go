@@ -0,0 +1,94 @@
+package testcontainers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestDetectRuntime(t *testing.T) {
+	detect := func(env map[string]string, sockets ...string) (Runtime, error) {
+		return detectRuntime(func(name string) string {
+			return env[name]
+		}, func(path string) bool {
+			for _, socket := range sockets {
+				if socket == path {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	xdg := map[string]string{"XDG_RUNTIME_DIR": "/run/user/1000"}
+
+	runtime, err := detect(map[string]string{"DOCKER_HOST": "tcp://10.0.0.2:2376"}, "/var/run/docker.sock")
+	require.NoError(t, err)
+	require.Equal(t, Runtime{Kind: RuntimeDocker, Host: "tcp://10.0.0.2:2376", Source: "DOCKER_HOST"}, runtime)
+
+	runtime, err = detect(xdg, "/var/run/docker.sock", "/run/user/1000/docker.sock")
+	require.NoError(t, err)
+	require.Equal(t, Runtime{Kind: RuntimeDocker, Host: "unix:///var/run/docker.sock", Source: "/var/run/docker.sock"}, runtime)
+
+	runtime, err = detect(xdg, "/run/user/1000/docker.sock", "/run/user/1000/podman/podman.sock")
+	require.NoError(t, err)
+	require.Equal(t, RuntimeDockerRootless, runtime.Kind)
+	require.Equal(t, "unix:///run/user/1000/docker.sock", runtime.Host)
+
+	runtime, err = detect(xdg, "/run/user/1000/podman/podman.sock")
+	require.NoError(t, err)
+	require.Equal(t, RuntimePodman, runtime.Kind)
+	require.Equal(t, testcontainers.ProviderPodman, runtime.ProviderType())
+
+	runtime, err = detect(nil, "/run/podman/podman.sock")
+	require.NoError(t, err)
+	require.Equal(t, Runtime{Kind: RuntimePodman, Host: "unix:///run/podman/podman.sock", Source: "/run/podman/podman.sock", ReaperPrivileged: true}, runtime)
+
+	runtime, err = detect(map[string]string{"TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED": "false"}, "/run/podman/podman.sock")
+	require.NoError(t, err)
+	require.False(t, runtime.ReaperPrivileged)
+
+	runtime, err = detect(map[string]string{"DOCKER_HOST": "unix:///run/user/1000/docker.sock"})
+	require.NoError(t, err)
+	require.Equal(t, RuntimeDockerRootless, runtime.Kind)
+	require.Equal(t, testcontainers.ProviderDocker, runtime.ProviderType())
+
+	_, err = detect(map[string]string{"DOCKER_HOST": "ssh://ci@build-host"})
+	require.ErrorContains(t, err, "forward the socket")
+
+	_, err = detect(xdg)
+	require.ErrorIs(t, err, ErrNoRuntime)
+}
+
+func TestCheckReaper(t *testing.T) {
+	rootless := Runtime{Kind: RuntimeDockerRootless, Host: "unix:///run/user/1000/docker.sock"}
+	require.ErrorContains(t, checkReaper(rootless, testcontainers.TestcontainersConfig{Host: "unix:///var/run/docker.sock"}),
+		"set DOCKER_HOST=unix:///run/user/1000/docker.sock")
+	require.NoError(t, checkReaper(rootless, testcontainers.TestcontainersConfig{Host: rootless.Host}))
+	require.NoError(t, checkReaper(rootless, testcontainers.TestcontainersConfig{RyukDisabled: true}))
+
+	podman := Runtime{Kind: RuntimePodman, Host: "unix:///run/podman/podman.sock", ReaperPrivileged: true}
+	require.ErrorContains(t, checkReaper(podman, testcontainers.TestcontainersConfig{Host: podman.Host}),
+		"set TESTCONTAINERS_RYUK_CONTAINER_PRIVILEGED=true")
+	require.NoError(t, checkReaper(podman, testcontainers.TestcontainersConfig{Host: podman.Host, RyukPrivileged: true}))
+
+	// the reaper mounts the default socket for remote daemons anyway
+	remote := Runtime{Kind: RuntimeDocker, Host: "tcp://10.0.0.2:2376"}
+	require.NoError(t, checkReaper(remote, testcontainers.TestcontainersConfig{Host: "unix:///var/run/docker.sock"}))
+}
+
+func TestRuntimeRemoteHost(t *testing.T) {
+	for host, want := range map[string]string{
+		"tcp://10.0.0.2:2376":            "10.0.0.2",
+		"tcp://docker:2375":              "docker",
+		"tcp://0.0.0.0:2375":             "localhost",
+		"https://[fd00::2]:2376":         "fd00::2",
+		"unix:///var/run/docker.sock":    "",
+		"npipe:////./pipe/docker_engine": "",
+	} {
+		got, remote, err := Runtime{Host: host}.remoteHost()
+		require.NoError(t, err, host)
+		require.Equal(t, want != "", remote, host)
+		require.Equal(t, want, got, host)
+	}
+}
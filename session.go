@@ -0,0 +1,152 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+const (
+	// LabelLibrary marks every container and network created by this library
+	LabelLibrary = "com.github.mmadfox.testcontainers"
+	// LabelSessionID holds the id of the process that created the container or network
+	LabelSessionID = "com.github.mmadfox.testcontainers.session-id"
+	// LabelTest holds the name of the test that created the container or network
+	LabelTest = "com.github.mmadfox.testcontainers.test"
+)
+
+var (
+	sessionOnce sync.Once
+	sessionID   string
+)
+
+// SessionID returns the id shared by all containers and networks created by this process
+func SessionID() string {
+	sessionOnce.Do(func() {
+		sessionID = UniqueID()
+	})
+	return sessionID
+}
+
+// SessionLabels returns the labels identifying the library, the current session and the creating test
+func SessionLabels(testName string) map[string]string {
+	labels := map[string]string{
+		LabelLibrary:   "true",
+		LabelSessionID: SessionID(),
+	}
+	if testName != "" {
+		labels[LabelTest] = testName
+	}
+	return labels
+}
+
+// WithSessionLabels stamps a container request with the session labels.
+// Labels set by the caller are kept unless they collide with a session label.
+func WithSessionLabels(req *testcontainers.ContainerRequest, testName string) {
+	req.Labels = withLabels(req.Labels, SessionLabels(testName))
+}
+
+// withLabels returns a copy of labels with extra added, the caller's map may be shared between starts
+func withLabels(labels map[string]string, extra map[string]string) map[string]string {
+	merged := make(map[string]string, len(labels)+len(extra))
+	for k, v := range labels {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ReapMinAge is the age below which the resources of other sessions are kept
+// unless ReapFilter selects a session or an age itself
+var ReapMinAge = time.Hour
+
+// ReapFilter selects the containers and networks removed by Reap.
+// The zero filter removes the resources of other sessions older than ReapMinAge, so that
+// sessions running concurrently, e.g. the other packages of go test ./..., survive.
+type ReapFilter struct {
+	// SessionID limits reaping to one session. When empty, every session
+	// except the current one is considered stale once older than OlderThan.
+	SessionID string
+	// Test limits reaping to resources created by the named test
+	Test string
+	// OlderThan only reaps resources created more than this long ago.
+	// When zero it is ReapMinAge, unless SessionID is set. A negative value reaps regardless of age.
+	OlderThan time.Duration
+}
+
+func (f ReapFilter) match(labels map[string]string, created time.Time) bool {
+	if labels[LabelLibrary] != "true" {
+		return false
+	}
+	session := labels[LabelSessionID]
+	if f.SessionID != "" {
+		if session != f.SessionID {
+			return false
+		}
+	} else if session == SessionID() {
+		return false
+	}
+	if f.Test != "" && labels[LabelTest] != f.Test {
+		return false
+	}
+	olderThan := f.OlderThan
+	if olderThan == 0 && f.SessionID == "" {
+		olderThan = ReapMinAge
+	}
+	if olderThan > 0 && time.Since(created) < olderThan {
+		return false
+	}
+	return true
+}
+
+// Reap removes the containers and networks left behind by stale sessions,
+// e.g. by a test binary that was killed before it could clean up.
+// Every matching resource is attempted, the returned error joins all failures.
+func Reap(ctx context.Context, filter ReapFilter) error {
+	client, err := NewDockerClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	args := filters.NewArgs(filters.Arg("label", LabelLibrary+"=true"))
+	containers, err := client.ContainerList(ctx, types.ContainerListOptions{All: true, Filters: args})
+	if err != nil {
+		return fmt.Errorf("failed to list containers: %v", err)
+	}
+	var errs []error
+	for _, c := range containers {
+		if !filter.match(c.Labels, time.Unix(c.Created, 0)) {
+			continue
+		}
+		err := client.ContainerRemove(ctx, c.ID, types.ContainerRemoveOptions{Force: true, RemoveVolumes: true})
+		if err != nil && !dockerclient.IsErrNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to remove container %s: %v", c.ID, err))
+		}
+	}
+
+	// networks go last, they cannot be removed while containers are attached
+	networks, err := client.NetworkList(ctx, types.NetworkListOptions{Filters: args})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("failed to list networks: %v", err))
+		return errors.Join(errs...)
+	}
+	for _, n := range networks {
+		if !filter.match(n.Labels, n.Created) {
+			continue
+		}
+		if err := client.NetworkRemove(ctx, n.ID); err != nil && !dockerclient.IsErrNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to remove network %s: %v", n.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
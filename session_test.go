@@ -0,0 +1,68 @@
+package testcontainers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestSessionLabels(t *testing.T) {
+	labels := SessionLabels(t.Name())
+	require.Equal(t, "true", labels[LabelLibrary])
+	require.Equal(t, SessionID(), labels[LabelSessionID])
+	require.Equal(t, t.Name(), labels[LabelTest])
+
+	require.NotContains(t, SessionLabels(""), LabelTest)
+}
+
+func TestWithSessionLabels(t *testing.T) {
+	shared := map[string]string{"app": "api"}
+	req := testcontainers.ContainerRequest{Labels: shared}
+	WithSessionLabels(&req, t.Name())
+	require.Equal(t, "api", req.Labels["app"])
+	require.Equal(t, t.Name(), req.Labels[LabelTest])
+	// the labels of one start do not leak into the next one
+	require.Equal(t, map[string]string{"app": "api"}, shared)
+}
+
+func TestReapFilter(t *testing.T) {
+	stale := map[string]string{
+		LabelLibrary:   "true",
+		LabelSessionID: "stale-session",
+		LabelTest:      "TestStale",
+	}
+	current := SessionLabels("TestCurrent")
+	foreign := map[string]string{"com.example": "true"}
+	old := time.Now().Add(-2 * ReapMinAge)
+	recent := time.Now().Add(-time.Minute)
+
+	testCases := []struct {
+		name    string
+		filter  ReapFilter
+		labels  map[string]string
+		created time.Time
+		match   bool
+	}{
+		{name: "stale session", labels: stale, created: old, match: true},
+		{name: "current session", labels: current, created: old, match: false},
+		{name: "foreign resource", labels: foreign, created: old, match: false},
+		{name: "explicit session", filter: ReapFilter{SessionID: SessionID()}, labels: current, created: old, match: true},
+		{name: "other session", filter: ReapFilter{SessionID: "other"}, labels: stale, created: old, match: false},
+		{name: "test matches", filter: ReapFilter{Test: "TestStale"}, labels: stale, created: old, match: true},
+		{name: "test differs", filter: ReapFilter{Test: "TestOther"}, labels: stale, created: old, match: false},
+		{name: "old enough", filter: ReapFilter{OlderThan: time.Minute}, labels: stale, created: old, match: true},
+		{name: "too young", filter: ReapFilter{OlderThan: time.Minute}, labels: stale, created: time.Now(), match: false},
+		// go test ./... runs the packages as concurrent sessions
+		{name: "concurrent session", labels: stale, created: recent, match: false},
+		{name: "concurrent session of a test", filter: ReapFilter{Test: "TestStale"}, labels: stale, created: recent, match: false},
+		{name: "explicit session of any age", filter: ReapFilter{SessionID: "stale-session"}, labels: stale, created: recent, match: true},
+		{name: "any age", filter: ReapFilter{OlderThan: -1}, labels: stale, created: recent, match: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.match, tc.filter.match(tc.labels, tc.created))
+		})
+	}
+}
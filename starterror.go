@@ -0,0 +1,385 @@
+package testcontainers
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+var (
+	// ErrStartupTimeout matches a StartError caused by a deadline, usually of the wait strategy
+	ErrStartupTimeout = errors.New("container startup timed out")
+	// ErrImagePull matches a StartError caused by an image that could not be pulled
+	ErrImagePull = errors.New("failed to pull image")
+	// ErrPortConflict matches a StartError caused by a host port that is already allocated
+	ErrPortConflict = errors.New("host port is already allocated")
+)
+
+// Phases of StartContainer, modules add their own after it
+const (
+	PhasePull   = "pull"
+	PhaseCreate = "create"
+	PhaseStart  = "start"
+	PhaseWait   = "wait"
+	// PhaseInspect follows StartContainer in modules that look up the host, ports and config of the container
+	PhaseInspect = "inspect"
+	// PhaseConfig is reported when the configuration or the reaper image could not be loaded
+	PhaseConfig = "config"
+)
+
+// startErrorLogLines is the number of log lines kept in a StartError
+const startErrorLogLines = 50
+
+// Phase is a timed step of a module start
+type Phase struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+}
+
+// StartError describes a module that failed to start, it is collected before
+// the failed container is removed
+type StartError struct {
+	Module string
+	// Phase is the name of the phase that failed
+	Phase  string
+	Phases []Phase
+	// Request is the effective request after merging the user's options
+	Request testcontainers.ContainerRequest
+	// WaitStrategy is the strategy that did not succeed when Phase is PhaseWait
+	WaitStrategy wait.Strategy
+	// State is the docker inspect state, nil if no container was created
+	State *types.ContainerState
+	// Logs are the last lines printed by the container
+	Logs []string
+	Err  error
+}
+
+// Error formats the failure followed by the container state and logs
+func (e *StartError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to start %s", e.Module)
+	if e.Request.Image != "" && e.Request.Image != e.Module {
+		fmt.Fprintf(&b, " (%s)", e.Request.Image)
+	}
+	fmt.Fprintf(&b, " in phase %s", e.Phase)
+	if e.Phase == PhaseWait && e.WaitStrategy != nil {
+		fmt.Fprintf(&b, " (%T)", e.WaitStrategy)
+	}
+	fmt.Fprintf(&b, " after %s: %v", e.elapsed().Round(time.Millisecond), e.Err)
+	if e.State != nil {
+		fmt.Fprintf(&b, "\nstate: %s, exit code %d", e.State.Status, e.State.ExitCode)
+		if e.State.OOMKilled {
+			b.WriteString(", OOM killed")
+		}
+		if e.State.Error != "" {
+			fmt.Fprintf(&b, ", error %q", e.State.Error)
+		}
+	}
+	if len(e.Logs) > 0 {
+		fmt.Fprintf(&b, "\nlast %d log lines:", len(e.Logs))
+		for _, line := range e.Logs {
+			b.WriteString("\n  ")
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the cause and the matching sentinel errors
+func (e *StartError) Unwrap() []error {
+	errs := []error{e.Err}
+	msg := ""
+	if e.Err != nil {
+		msg = e.Err.Error()
+	}
+	if IsPortConflict(e.Err) {
+		errs = append(errs, ErrPortConflict)
+	}
+	if e.Phase == PhasePull && (strings.Contains(msg, "pull") || strings.Contains(msg, "No such image") || strings.Contains(msg, "manifest unknown")) {
+		errs = append(errs, ErrImagePull)
+	}
+	if errors.Is(e.Err, context.DeadlineExceeded) || strings.Contains(msg, "deadline exceeded") {
+		errs = append(errs, ErrStartupTimeout)
+	}
+	return errs
+}
+
+func (e *StartError) elapsed() time.Duration {
+	var d time.Duration
+	for _, phase := range e.Phases {
+		d += phase.Duration
+	}
+	return d
+}
+
+// Startup records the phases of a module start and turns failures into a *StartError
+type Startup struct {
+	module string
+	id     uint64
+
+	mu      sync.Mutex
+	hooks   []StartupHook
+	phases  []Phase
+	current string
+	since   time.Time
+	request testcontainers.ContainerRequest
+	// finished is set once Done or Fail emitted the last event
+	finished bool
+}
+
+// NewStartup starts timing the start of a module
+func NewStartup(module string) *Startup {
+	return &Startup{module: module, id: startupIDs.Add(1)}
+}
+
+// Phase ends the current phase and begins the named one
+func (s *Startup) Phase(name string) {
+	s.mu.Lock()
+	ended, ok := s.endPhase()
+	s.current, s.since = name, time.Now()
+	s.mu.Unlock()
+	if ok {
+		s.emit(ended, nil)
+	}
+}
+
+// Done ends the current phase and the start, it is called once the module is ready
+func (s *Startup) Done() {
+	s.mu.Lock()
+	phase, last := s.endStart()
+	s.mu.Unlock()
+	if last {
+		s.emitLast(phase, nil)
+	}
+}
+
+// Phases returns the finished phases and the elapsed time of the current one
+func (s *Startup) Phases() []Phase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	phases := append([]Phase(nil), s.phases...)
+	if s.current != "" {
+		phases = append(phases, Phase{Name: s.current, Start: s.since, Duration: time.Since(s.since)})
+	}
+	return phases
+}
+
+func (s *Startup) endPhase() (Phase, bool) {
+	if s.current == "" {
+		return Phase{}, false
+	}
+	phase := Phase{Name: s.current, Start: s.since, Duration: time.Since(s.since)}
+	s.phases = append(s.phases, phase)
+	s.current = ""
+	return phase, true
+}
+
+// endStart ends the current phase and the start, last is false when the start already ended.
+// It is called with s.mu held.
+func (s *Startup) endStart() (phase Phase, last bool) {
+	if s.finished {
+		return Phase{}, false
+	}
+	s.finished = true
+	phase, ok := s.endPhase()
+	if !ok {
+		phase = Phase{Start: time.Now()}
+	}
+	return phase, true
+}
+
+// StartContainer creates and starts a container, a start failing because of a port
+// conflict is retried after removing the failed container.
+// On failure the container is removed, unless Config.KeepFailed is set, and a *StartError is returned,
+// see Fail.
+func (s *Startup) StartContainer(ctx context.Context, req testcontainers.ContainerRequest) (testcontainers.Container, error) {
+	s.mu.Lock()
+	s.request = req
+	s.hooks = append(s.hooks, startupHooks(ctx)...)
+	s.mu.Unlock()
+
+	config, err := LoadConfig()
+	if err != nil {
+		s.Phase(PhaseConfig)
+		return nil, s.Fail(nil, err)
+	}
+	if config.Offline {
+		images, err := requiredImages()
+		if err != nil {
+			s.Phase(PhaseConfig)
+			return nil, s.Fail(nil, err)
+		}
+		if err := PreflightImages(ctx, append(images, req.Image), config.ImageArchives); err != nil {
+			s.Phase(PhasePull)
+			return nil, s.Fail(nil, err)
+		}
+	}
+	if req.ReaperOptions, err = withReaperImage(req.ReaperOptions); err != nil {
+		s.Phase(PhaseConfig)
+		return nil, s.Fail(nil, err)
+	}
+
+	hooks := append([]testcontainers.ContainerLifecycleHooks(nil), req.LifecycleHooks...)
+	req.LifecycleHooks = append(hooks, testcontainers.ContainerLifecycleHooks{
+		PreCreates: []testcontainers.ContainerRequestHook{func(context.Context, testcontainers.ContainerRequest) error {
+			s.Phase(PhaseCreate)
+			return nil
+		}},
+		PreStarts: []testcontainers.ContainerHook{func(context.Context, testcontainers.Container) error {
+			s.Phase(PhaseStart)
+			return nil
+		}},
+	})
+	if req.WaitingFor != nil {
+		req.WaitingFor = &phaseStrategy{Strategy: req.WaitingFor, begin: func() { s.Phase(PhaseWait) }}
+	}
+
+	provider, err := newDockerProvider()
+	if err != nil {
+		s.Phase(PhaseConfig)
+		return nil, s.Fail(nil, err)
+	}
+
+	// reuse looks containers up by name
+	reuse := config.Reuse && req.Name != ""
+	for attempt := 1; ; attempt++ {
+		s.Phase(PhasePull)
+		c, err := startContainer(ctx, provider, req, reuse)
+		// reused containers are meant to outlive the process, see CheckLeaks
+		if c != nil && !reuse {
+			trackContainer(c.GetContainerID(), req.Name)
+		}
+		if err == nil {
+			s.Phase("")
+			return c, nil
+		}
+		if !IsPortConflict(err) || attempt == portConflictAttempts {
+			return nil, s.Fail(c, err)
+		}
+		if c != nil {
+			_ = c.Terminate(ctx)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, s.Fail(nil, ctx.Err())
+		case <-time.After(time.Duration(attempt) * 500 * time.Millisecond):
+		}
+	}
+}
+
+// reuseMu serializes the lookup of reused containers, so that parallel tests share one
+var reuseMu sync.Mutex
+
+// startContainer is testcontainers.GenericContainer with a given provider
+func startContainer(ctx context.Context, provider *testcontainers.DockerProvider, req testcontainers.ContainerRequest, reuse bool) (testcontainers.Container, error) {
+	var c testcontainers.Container
+	var err error
+	if reuse {
+		reuseMu.Lock()
+		defer reuseMu.Unlock()
+		c, err = provider.ReuseOrCreateContainer(ctx, req)
+	} else {
+		c, err = provider.CreateContainer(ctx, req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create container", err)
+	}
+	if !c.IsRunning() {
+		if err := c.Start(ctx); err != nil {
+			return c, fmt.Errorf("%w: failed to start container", err)
+		}
+	}
+	return c, nil
+}
+
+// Fail builds a *StartError for the current phase with the state and logs of c, which may be nil,
+// and ends the phase. c is removed afterwards, unless Config.KeepFailed is set.
+func (s *Startup) Fail(c testcontainers.Container, err error) error {
+	s.mu.Lock()
+	phase := s.current
+	if phase == "" {
+		phase = "ready"
+	}
+	startErr := &StartError{
+		Module:  s.module,
+		Phase:   phase,
+		Request: s.request,
+		Err:     err,
+	}
+	ended, last := s.endStart()
+	startErr.Phases = append([]Phase(nil), s.phases...)
+	s.mu.Unlock()
+	if last {
+		s.emitLast(ended, err)
+	}
+	if phase == PhaseWait {
+		startErr.WaitStrategy = startErr.Request.WaitingFor
+	}
+	if c == nil {
+		return startErr
+	}
+
+	// the start context has often expired when the wait strategy timed out
+	diagCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if state, err := c.State(diagCtx); err == nil {
+		startErr.State = state
+	}
+	startErr.Logs = logsTail(diagCtx, c, startErrorLogLines)
+
+	if config, err := LoadConfig(); err == nil && config.KeepFailed {
+		untrackContainer(c.GetContainerID())
+	} else {
+		_ = c.Terminate(context.Background())
+	}
+	return startErr
+}
+
+func logsTail(ctx context.Context, c testcontainers.Container, n int) []string {
+	r, err := c.Logs(ctx)
+	if err != nil {
+		return nil
+	}
+	defer r.Close()
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
+
+// phaseStrategy marks the beginning of the wait phase
+type phaseStrategy struct {
+	wait.Strategy
+	begin func()
+}
+
+func (s *phaseStrategy) WaitUntilReady(ctx context.Context, target wait.StrategyTarget) error {
+	s.begin()
+	return s.Strategy.WaitUntilReady(ctx, target)
+}
+
+// StartContainer is Startup.StartContainer for modules that do not time their own phases
+func StartContainer(ctx context.Context, req testcontainers.ContainerRequest) (testcontainers.Container, error) {
+	startup := NewStartup(req.Image)
+	c, err := startup.StartContainer(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	startup.Done()
+	return c, nil
+}
@@ -0,0 +1,129 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// exitedContainer is a container that died during startup
+type exitedContainer struct {
+	testcontainers.Container
+	logs       string
+	terminated bool
+}
+
+func (c *exitedContainer) GetContainerID() string {
+	return "0123456789ab"
+}
+
+func (c *exitedContainer) Terminate(context.Context) error {
+	c.terminated = true
+	return nil
+}
+
+func (c *exitedContainer) State(context.Context) (*types.ContainerState, error) {
+	return &types.ContainerState{Status: "exited", ExitCode: 137, OOMKilled: true}, nil
+}
+
+func (c *exitedContainer) Logs(context.Context) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(c.logs)), nil
+}
+
+func TestStartupFail(t *testing.T) {
+	isolateConfig(t)
+	var logs strings.Builder
+	for i := 1; i <= startErrorLogLines+10; i++ {
+		fmt.Fprintf(&logs, "line %d\n", i)
+	}
+	c := &exitedContainer{logs: logs.String()}
+	strategy := wait.ForListeningPort("27017")
+
+	startup := NewStartup("mongo")
+	startup.request = testcontainers.ContainerRequest{Image: "mongo:6.0", WaitingFor: strategy}
+	startup.Phase(PhasePull)
+	startup.Phase(PhaseWait)
+	err := startup.Fail(c, fmt.Errorf("%w: failed to start container", context.DeadlineExceeded))
+
+	var startErr *StartError
+	require.True(t, errors.As(err, &startErr))
+	require.Equal(t, "mongo", startErr.Module)
+	require.Equal(t, PhaseWait, startErr.Phase)
+	require.Equal(t, "mongo:6.0", startErr.Request.Image)
+	require.Equal(t, strategy, startErr.WaitStrategy)
+	require.Equal(t, []string{PhasePull, PhaseWait}, []string{startErr.Phases[0].Name, startErr.Phases[1].Name})
+	require.Equal(t, 137, startErr.State.ExitCode)
+	require.True(t, startErr.State.OOMKilled)
+	require.Len(t, startErr.Logs, startErrorLogLines)
+	require.Equal(t, fmt.Sprintf("line %d", startErrorLogLines+10), startErr.Logs[len(startErr.Logs)-1])
+
+	require.ErrorIs(t, err, ErrStartupTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NotErrorIs(t, err, ErrImagePull)
+	require.NotErrorIs(t, err, ErrPortConflict)
+
+	msg := err.Error()
+	require.True(t, strings.HasPrefix(msg, "failed to start mongo (mongo:6.0) in phase wait (*wait.HostPortStrategy) after "))
+	require.Contains(t, msg, "state: exited, exit code 137, OOM killed")
+	require.Contains(t, msg, "line 60")
+	require.True(t, c.terminated)
+}
+
+func TestStartupFailKeepFailed(t *testing.T) {
+	isolateConfig(t)
+	writeConfig(t, "keep_failed: true\n")
+	c := &exitedContainer{}
+	trackContainer(c.GetContainerID(), "mongo")
+	_ = NewStartup("mongo").Fail(c, errors.New("boom"))
+	require.False(t, c.terminated)
+	created.mu.Lock()
+	_, tracked := created.containers[c.GetContainerID()]
+	created.mu.Unlock()
+	require.False(t, tracked)
+}
+
+func TestStartContainerConfigError(t *testing.T) {
+	isolateConfig(t)
+	writeConfig(t, "startup_timeot: 2m\n")
+	_, err := NewStartup("redis").StartContainer(context.Background(), testcontainers.ContainerRequest{Image: "redis:7.0.5"})
+	var startErr *StartError
+	require.ErrorAs(t, err, &startErr)
+	require.Equal(t, PhaseConfig, startErr.Phase)
+	require.ErrorContains(t, err, "field startup_timeot not found")
+}
+
+func TestStartErrorSentinels(t *testing.T) {
+	pull := &StartError{Phase: PhasePull, Err: errors.New("Error response from daemon: pull access denied for mongo-typo")}
+	require.ErrorIs(t, pull, ErrImagePull)
+	require.NotErrorIs(t, pull, ErrStartupTimeout)
+
+	conflict := &StartError{Phase: PhaseStart, Err: errors.New("Bind for 0.0.0.0:2189 failed: port is already allocated")}
+	require.ErrorIs(t, conflict, ErrPortConflict)
+	require.NotErrorIs(t, conflict, ErrImagePull)
+
+	// wrapped by a module
+	err := fmt.Errorf("failed to start zookeeper container: %w", conflict)
+	require.ErrorIs(t, err, ErrPortConflict)
+}
+
+func TestStartupPhases(t *testing.T) {
+	startup := NewStartup("kafka")
+	require.Empty(t, startup.Phases())
+	startup.Phase(PhasePull)
+	startup.Phase(PhaseCreate)
+	startup.Phase("")
+	phases := startup.Phases()
+	require.Len(t, phases, 2)
+	require.Equal(t, PhaseCreate, phases[1].Name)
+
+	err := startup.Fail(nil, errors.New("boom"))
+	require.Contains(t, err.Error(), "in phase ready")
+}
@@ -0,0 +1,526 @@
+// Package tctest provides an in-memory testcontainers.Container to unit test code
+// built on containers, e.g. tc.ExecCmd or tc.StartLogger, without a docker daemon.
+package tctest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+	tc "github.com/mmadfox/testcontainers"
+	"github.com/testcontainers/testcontainers-go"
+	tcexec "github.com/testcontainers/testcontainers-go/exec"
+)
+
+// ErrNotRunning is returned by Exec when the container is not running
+var ErrNotRunning = errors.New("container is not running")
+
+// Frame is a frame of the multiplexed output of an exec
+type Frame struct {
+	Stream tc.StreamType
+	Data   string
+}
+
+// ExecResult is the scripted outcome of an exec
+type ExecResult struct {
+	ExitCode int
+	// Frames are sent first, then Stdout and Stderr as one frame each when not empty
+	Frames []Frame
+	Stdout string
+	Stderr string
+	// Err fails the exec call itself, like an unreachable daemon
+	Err error
+}
+
+func (r ExecResult) output() []byte {
+	frames := append([]Frame(nil), r.Frames...)
+	if r.Stdout != "" {
+		frames = append(frames, Frame{Stream: tc.Stdout, Data: r.Stdout})
+	}
+	if r.Stderr != "" {
+		frames = append(frames, Frame{Stream: tc.Stderr, Data: r.Stderr})
+	}
+	var buf bytes.Buffer
+	for _, frame := range frames {
+		header := [8]byte{byte(frame.Stream)}
+		binary.BigEndian.PutUint32(header[4:], uint32(len(frame.Data)))
+		buf.Write(header[:])
+		buf.WriteString(frame.Data)
+	}
+	return buf.Bytes()
+}
+
+type execScript struct {
+	prefix  []string
+	results []ExecResult
+}
+
+func (s *execScript) match(cmd []string) bool {
+	if len(cmd) < len(s.prefix) {
+		return false
+	}
+	for i, arg := range s.prefix {
+		if cmd[i] != arg {
+			return false
+		}
+	}
+	return true
+}
+
+// Container is a scripted testcontainers.Container. It starts running, with no ports
+// mapped, no exec scripted and no logs. It is safe for concurrent use.
+type Container struct {
+	mu         sync.Mutex
+	id         string
+	name       string
+	host       string
+	states     []types.ContainerState
+	ports      map[nat.Port]nat.Port
+	networks   map[string][]string
+	ips        []string
+	execs      []*execScript
+	executed   [][]string
+	logs       []testcontainers.Log
+	consumers  []testcontainers.LogConsumer
+	producing  bool
+	files      map[string][]byte
+	terminated bool
+	sessionID  string
+	// sent is the number of logs sent to the consumers
+	sent int
+}
+
+var _ testcontainers.Container = (*Container)(nil)
+
+// NewContainer returns a running container reachable at localhost
+func NewContainer(name string) *Container {
+	id := make([]byte, 32)
+	_, _ = rand.Read(id)
+	return &Container{
+		id:        hex.EncodeToString(id),
+		name:      name,
+		host:      "localhost",
+		states:    []types.ContainerState{running()},
+		ports:     make(map[nat.Port]nat.Port),
+		networks:  make(map[string][]string),
+		files:     make(map[string][]byte),
+		sessionID: tc.SessionID(),
+	}
+}
+
+func running() types.ContainerState {
+	return types.ContainerState{
+		Status:    "running",
+		Running:   true,
+		StartedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// SetHost sets the address returned by Host
+func (c *Container) SetHost(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.host = host
+}
+
+// MapPort publishes a container port, e.g. "6379/tcp", on a host port
+func (c *Container) MapPort(port nat.Port, hostPort int) {
+	port = withProto(port)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ports[port] = nat.Port(fmt.Sprintf("%d/%s", hostPort, port.Proto()))
+}
+
+// withProto defaults the protocol of a port to tcp like docker does
+func withProto(port nat.Port) nat.Port {
+	if !strings.Contains(string(port), "/") {
+		return nat.Port(string(port) + "/tcp")
+	}
+	return port
+}
+
+// Connect attaches the container to a network under aliases with an ip address
+func (c *Container) Connect(network, ip string, aliases ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.networks[network] = aliases
+	c.ips = append(c.ips, ip)
+}
+
+// SetState replaces the state of the container, and any scripted transition
+func (c *Container) SetState(state types.ContainerState) {
+	c.Transition(state)
+}
+
+// Transition scripts the states returned by successive calls to State,
+// the last one is kept, e.g. a container starting and crashing:
+//
+//	c.Transition(tctest.Running(), tctest.Exited(1))
+func (c *Container) Transition(states ...types.ContainerState) {
+	if len(states) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.states = append([]types.ContainerState(nil), states...)
+}
+
+// Running is the state of a running container
+func Running() types.ContainerState {
+	return running()
+}
+
+// Exited is the state of a container whose main process exited with code
+func Exited(code int) types.ContainerState {
+	return types.ContainerState{
+		Status:     "exited",
+		ExitCode:   code,
+		FinishedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// OnExec scripts the results of the commands starting with prefix, an empty prefix
+// matches every command. Results are returned in order and the last one is repeated.
+// Scripts are matched in the order they were added.
+func (c *Container) OnExec(prefix []string, results ...ExecResult) {
+	if len(results) == 0 {
+		results = []ExecResult{{}}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.execs = append(c.execs, &execScript{prefix: prefix, results: results})
+}
+
+// Executed returns the commands executed so far
+func (c *Container) Executed() [][]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]string(nil), c.executed...)
+}
+
+// Log adds stdout lines to the logs, they are sent to the consumers when the log producer runs
+func (c *Container) Log(lines ...string) {
+	c.log(testcontainers.StdoutLog, lines)
+}
+
+// LogStderr adds stderr lines to the logs
+func (c *Container) LogStderr(lines ...string) {
+	c.log(testcontainers.StderrLog, lines)
+}
+
+func (c *Container) log(logType string, lines []string) {
+	c.mu.Lock()
+	for _, line := range lines {
+		c.logs = append(c.logs, testcontainers.Log{LogType: logType, Content: []byte(line + "\n")})
+	}
+	c.mu.Unlock()
+	c.deliver()
+}
+
+// deliver sends the logs not sent yet while the producer runs, consumers are called without the lock
+func (c *Container) deliver() {
+	c.mu.Lock()
+	if !c.producing {
+		c.mu.Unlock()
+		return
+	}
+	logs := c.logs[c.sent:]
+	c.sent = len(c.logs)
+	consumers := append([]testcontainers.LogConsumer(nil), c.consumers...)
+	c.mu.Unlock()
+	for _, l := range logs {
+		for _, consumer := range consumers {
+			consumer.Accept(l)
+		}
+	}
+}
+
+// File returns the content of a file copied into the container
+func (c *Container) File(path string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	content, ok := c.files[path]
+	return content, ok
+}
+
+// Terminated reports whether Terminate was called
+func (c *Container) Terminated() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.terminated
+}
+
+func (c *Container) GetContainerID() string {
+	return c.id
+}
+
+// Endpoint returns proto://host:port of the lowest mapped port
+func (c *Container) Endpoint(ctx context.Context, proto string) (string, error) {
+	c.mu.Lock()
+	ports := make([]nat.Port, 0, len(c.ports))
+	for port := range c.ports {
+		ports = append(ports, port)
+	}
+	c.mu.Unlock()
+	if len(ports) == 0 {
+		return "", errors.New("no ports exposed")
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Int() < ports[j].Int() })
+	return c.PortEndpoint(ctx, ports[0], proto)
+}
+
+func (c *Container) PortEndpoint(ctx context.Context, port nat.Port, proto string) (string, error) {
+	host, err := c.Host(ctx)
+	if err != nil {
+		return "", err
+	}
+	mapped, err := c.MappedPort(ctx, port)
+	if err != nil {
+		return "", err
+	}
+	if proto != "" {
+		proto += "://"
+	}
+	return fmt.Sprintf("%s%s:%s", proto, host, mapped.Port()), nil
+}
+
+func (c *Container) Host(context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.host, nil
+}
+
+func (c *Container) MappedPort(_ context.Context, port nat.Port) (nat.Port, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	mapped, ok := c.ports[withProto(port)]
+	if !ok {
+		return "", fmt.Errorf("port %s not found", port)
+	}
+	return mapped, nil
+}
+
+func (c *Container) Ports(context.Context) (nat.PortMap, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ports := make(nat.PortMap, len(c.ports))
+	for port, mapped := range c.ports {
+		ports[port] = []nat.PortBinding{{HostIP: "0.0.0.0", HostPort: mapped.Port()}}
+	}
+	return ports, nil
+}
+
+func (c *Container) SessionID() string {
+	return c.sessionID
+}
+
+// IsRunning reports whether the current state is running, without moving to the next one
+func (c *Container) IsRunning() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return !c.terminated && c.states[0].Running
+}
+
+func (c *Container) Start(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.terminated {
+		return fmt.Errorf("no such container: %s", c.id)
+	}
+	c.states = []types.ContainerState{running()}
+	return nil
+}
+
+func (c *Container) Stop(context.Context, *time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.terminated {
+		return fmt.Errorf("no such container: %s", c.id)
+	}
+	c.states = []types.ContainerState{Exited(0)}
+	return nil
+}
+
+func (c *Container) Terminate(context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.terminated = true
+	c.producing = false
+	return nil
+}
+
+// Logs returns the lines logged so far
+func (c *Container) Logs(context.Context) (io.ReadCloser, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var buf bytes.Buffer
+	for _, l := range c.logs {
+		buf.Write(l.Content)
+	}
+	return io.NopCloser(&buf), nil
+}
+
+func (c *Container) FollowOutput(consumer testcontainers.LogConsumer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consumers = append(c.consumers, consumer)
+}
+
+// StartLogProducer sends the logs so far and every later line to the consumers
+func (c *Container) StartLogProducer(context.Context) error {
+	c.mu.Lock()
+	if c.producing {
+		c.mu.Unlock()
+		return errors.New("log producer already started")
+	}
+	c.producing = true
+	c.mu.Unlock()
+	c.deliver()
+	return nil
+}
+
+func (c *Container) StopLogProducer() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.producing = false
+	return nil
+}
+
+func (c *Container) Name(context.Context) (string, error) {
+	return "/" + c.name, nil
+}
+
+// State returns the next scripted state
+func (c *Container) State(context.Context) (*types.ContainerState, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.terminated {
+		return nil, fmt.Errorf("no such container: %s", c.id)
+	}
+	state := c.states[0]
+	if len(c.states) > 1 {
+		c.states = c.states[1:]
+	}
+	return &state, nil
+}
+
+func (c *Container) Networks(context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	networks := make([]string, 0, len(c.networks))
+	for network := range c.networks {
+		networks = append(networks, network)
+	}
+	sort.Strings(networks)
+	return networks, nil
+}
+
+func (c *Container) NetworkAliases(context.Context) (map[string][]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	aliases := make(map[string][]string, len(c.networks))
+	for network, names := range c.networks {
+		aliases[network] = append([]string(nil), names...)
+	}
+	return aliases, nil
+}
+
+// Exec returns the scripted result of the first script matching cmd.
+// The output is multiplexed like docker does, tcexec.Multiplexed demultiplexes it.
+func (c *Container) Exec(ctx context.Context, cmd []string, options ...tcexec.ProcessOption) (int, io.Reader, error) {
+	if !c.IsRunning() {
+		return 0, nil, ErrNotRunning
+	}
+	c.mu.Lock()
+	c.executed = append(c.executed, append([]string(nil), cmd...))
+	var result *ExecResult
+	for _, script := range c.execs {
+		if script.match(cmd) {
+			r := script.results[0]
+			if len(script.results) > 1 {
+				script.results = script.results[1:]
+			}
+			result = &r
+			break
+		}
+	}
+	c.mu.Unlock()
+
+	if result == nil {
+		return 0, nil, fmt.Errorf("no exec scripted for %q", cmd)
+	}
+	if result.Err != nil {
+		return 0, nil, result.Err
+	}
+	opts := &tcexec.ProcessOptions{Reader: bytes.NewReader(result.output())}
+	for _, o := range options {
+		o.Apply(opts)
+	}
+	return result.ExitCode, opts.Reader, nil
+}
+
+func (c *Container) ContainerIP(ctx context.Context) (string, error) {
+	ips, err := c.ContainerIPs(ctx)
+	if err != nil || len(ips) == 0 {
+		return "", err
+	}
+	return ips[0], nil
+}
+
+func (c *Container) ContainerIPs(context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.ips...), nil
+}
+
+func (c *Container) CopyToContainer(_ context.Context, fileContent []byte, containerFilePath string, _ int64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[containerFilePath] = append([]byte(nil), fileContent...)
+	return nil
+}
+
+// CopyDirToContainer copies hostDirPath to containerParentPath/base(hostDirPath)
+func (c *Container) CopyDirToContainer(ctx context.Context, hostDirPath string, containerParentPath string, fileMode int64) error {
+	return filepath.WalkDir(hostDirPath, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(filepath.Dir(hostDirPath), file)
+		if err != nil {
+			return err
+		}
+		return c.CopyFileToContainer(ctx, file, path.Join(containerParentPath, filepath.ToSlash(rel)), fileMode)
+	})
+}
+
+func (c *Container) CopyFileToContainer(ctx context.Context, hostFilePath string, containerFilePath string, fileMode int64) error {
+	content, err := os.ReadFile(hostFilePath)
+	if err != nil {
+		return err
+	}
+	return c.CopyToContainer(ctx, content, containerFilePath, fileMode)
+}
+
+func (c *Container) CopyFileFromContainer(_ context.Context, filePath string) (io.ReadCloser, error) {
+	content, ok := c.File(filePath)
+	if !ok {
+		return nil, fmt.Errorf("could not find the file %s in container %s", filePath, c.id)
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
@@ -0,0 +1,137 @@
+package tctest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	tc "github.com/mmadfox/testcontainers"
+	"github.com/stretchr/testify/require"
+	tcexec "github.com/testcontainers/testcontainers-go/exec"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestContainerExec(t *testing.T) {
+	ctx := context.Background()
+	c := NewContainer("test-redis")
+	c.OnExec([]string{"redis-cli", "ping"},
+		ExecResult{ExitCode: 1, Stderr: "Could not connect to Redis"},
+		ExecResult{Stdout: "PONG\n"},
+	)
+	c.OnExec([]string{"sh"}, ExecResult{Frames: []Frame{
+		{Stream: tc.Stdout, Data: "out 1\n"},
+		{Stream: tc.Stderr, Data: "err 1\n"},
+		{Stream: tc.Stdout, Data: "out 2\n"},
+	}})
+
+	_, err := tc.ExecCmd(ctx, c, []string{"redis-cli", "ping"})
+	require.ErrorContains(t, err, "exit code: 1")
+	output, err := tc.ExecCmd(ctx, c, []string{"redis-cli", "ping"})
+	require.NoError(t, err)
+	require.Equal(t, "PONG\n", output.Stdout)
+	// the last result is repeated
+	output, err = tc.ExecCmd(ctx, c, []string{"redis-cli", "ping", "-h", "localhost"})
+	require.NoError(t, err)
+	require.Equal(t, "PONG\n", output.Stdout)
+
+	output, err = tc.ExecCmd(ctx, c, []string{"sh", "-c", "true"})
+	require.NoError(t, err)
+	require.Equal(t, "out 1\nout 2\n", output.Stdout)
+	require.Equal(t, "err 1\n", output.Stderr)
+
+	_, r, err := c.Exec(ctx, []string{"sh"}, tcexec.Multiplexed())
+	require.NoError(t, err)
+	stdout, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "out 1\nout 2\n", string(stdout))
+
+	_, _, err = c.Exec(ctx, []string{"mongosh"})
+	require.ErrorContains(t, err, `no exec scripted for ["mongosh"]`)
+	require.Len(t, c.Executed(), 6)
+
+	c.OnExec(nil, ExecResult{Err: errors.New("daemon is gone")})
+	_, _, err = c.Exec(ctx, []string{"mongosh"})
+	require.ErrorContains(t, err, "daemon is gone")
+
+	c.SetState(Exited(137))
+	_, _, err = c.Exec(ctx, []string{"sh"})
+	require.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestContainerStateAndPorts(t *testing.T) {
+	ctx := context.Background()
+	c := NewContainer("test-mongo")
+	c.MapPort("27017", 49153)
+	c.Connect("backend", "172.18.0.2", "mongo")
+
+	endpoint, err := c.PortEndpoint(ctx, "27017/tcp", "mongodb")
+	require.NoError(t, err)
+	require.Equal(t, "mongodb://localhost:49153", endpoint)
+	addr, err := tc.ProbeEndpoint(ctx, c, "27017")
+	require.NoError(t, err)
+	require.Equal(t, "localhost:49153", addr)
+	_, err = c.MappedPort(ctx, "6379/tcp")
+	require.Error(t, err)
+
+	ips, err := c.ContainerIPs(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []string{"172.18.0.2"}, ips)
+	aliases, err := c.NetworkAliases(ctx)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"backend": {"mongo"}}, aliases)
+
+	c.Transition(Running(), Running(), Exited(1))
+	var statuses []string
+	for i := 0; i < 4; i++ {
+		state, err := c.State(ctx)
+		require.NoError(t, err)
+		statuses = append(statuses, state.Status)
+	}
+	require.Equal(t, []string{"running", "running", "exited", "exited"}, statuses)
+	require.False(t, c.IsRunning())
+
+	require.NoError(t, c.Start(ctx))
+	require.True(t, c.IsRunning())
+	require.NoError(t, c.Terminate(ctx))
+	require.True(t, c.Terminated())
+	_, err = c.State(ctx)
+	require.Error(t, err)
+}
+
+func TestContainerLogs(t *testing.T) {
+	ctx := context.Background()
+	c := NewContainer("test-kafka")
+	c.Log("starting")
+	c.LogStderr("WARN no listeners")
+
+	history := tc.NewRingBuffer(10)
+	logger, err := tc.StartLogger(ctx, c, tc.WithLogSink(history))
+	require.NoError(t, err)
+	c.Log("started (kafka.server.KafkaServer)")
+
+	line, err := logger.WaitForLog(ctx, tc.Contains("KafkaServer"))
+	require.NoError(t, err)
+	require.Equal(t, "test-kafka", line.Container)
+	require.NoError(t, logger.Stop())
+
+	lines := history.Lines()
+	require.Len(t, lines, 3)
+	require.Equal(t, tc.Stderr, lines[1].Stream)
+	require.Equal(t, "WARN no listeners", lines[1].Content)
+
+	r, err := c.Logs(ctx)
+	require.NoError(t, err)
+	logs, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "starting\nWARN no listeners\nstarted (kafka.server.KafkaServer)\n", string(logs))
+
+	// a probe on a crashed container fails fast
+	c.SetState(Exited(1))
+	err = tc.ForProbe(func(context.Context, wait.StrategyTarget) error {
+		return errors.New("connection refused")
+	}).
+		WithStartupTimeout(time.Second).WaitUntilReady(ctx, c)
+	require.ErrorContains(t, err, "exit code 1")
+}
@@ -0,0 +1,211 @@
+package testcontainers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+)
+
+// PhaseEvent is passed to the startup hooks when a phase of a module start ends
+type PhaseEvent struct {
+	// StartupID tells concurrent starts of the same module apart
+	StartupID uint64
+	Module    string
+	Phase     Phase
+	// Err is set when the phase failed
+	Err error
+	// Last is set on the event ending the start, emitted by Startup.Done and Startup.Fail.
+	// Its Phase has no name when no phase was running.
+	Last bool
+}
+
+// StartupHook observes the phases of module starts, it must be safe for concurrent use
+type StartupHook func(event PhaseEvent)
+
+var (
+	startupIDs atomic.Uint64
+
+	globalHooksMu sync.RWMutex
+	globalHooks   = make(map[uint64]StartupHook)
+	nextHookID    uint64
+)
+
+// AddStartupHook observes every module start of the process until remove is called
+func AddStartupHook(hook StartupHook) (remove func()) {
+	globalHooksMu.Lock()
+	defer globalHooksMu.Unlock()
+	nextHookID++
+	id := nextHookID
+	globalHooks[id] = hook
+	return func() {
+		globalHooksMu.Lock()
+		defer globalHooksMu.Unlock()
+		delete(globalHooks, id)
+	}
+}
+
+type startupHooksKey struct{}
+
+// WithStartupHook returns a context observing the module starts it is passed to
+func WithStartupHook(ctx context.Context, hook StartupHook) context.Context {
+	hooks := append([]StartupHook(nil), startupHooks(ctx)...)
+	return context.WithValue(ctx, startupHooksKey{}, append(hooks, hook))
+}
+
+func startupHooks(ctx context.Context) []StartupHook {
+	hooks, _ := ctx.Value(startupHooksKey{}).([]StartupHook)
+	return hooks
+}
+
+func (s *Startup) emit(phase Phase, err error) {
+	s.emitEvent(PhaseEvent{StartupID: s.id, Module: s.module, Phase: phase, Err: err})
+}
+
+func (s *Startup) emitLast(phase Phase, err error) {
+	s.emitEvent(PhaseEvent{StartupID: s.id, Module: s.module, Phase: phase, Err: err, Last: true})
+}
+
+func (s *Startup) emitEvent(event PhaseEvent) {
+	s.mu.Lock()
+	hooks := append([]StartupHook(nil), s.hooks...)
+	s.mu.Unlock()
+	globalHooksMu.RLock()
+	ids := make([]uint64, 0, len(globalHooks))
+	for id := range globalHooks {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		hooks = append(hooks, globalHooks[id])
+	}
+	globalHooksMu.RUnlock()
+	for _, hook := range hooks {
+		hook(event)
+	}
+}
+
+// StartupReport collects the phases of module starts, use Hook as a StartupHook
+type StartupReport struct {
+	mu     sync.Mutex
+	events []PhaseEvent
+}
+
+// Hook records an event
+func (r *StartupReport) Hook(event PhaseEvent) {
+	if event.Phase.Name == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Events returns the recorded events ordered by start
+func (r *StartupReport) Events() []PhaseEvent {
+	r.mu.Lock()
+	events := append([]PhaseEvent(nil), r.events...)
+	r.mu.Unlock()
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Phase.Start.Before(events[j].Phase.Start)
+	})
+	return events
+}
+
+// WriteTo writes a table of every phase and the total of every module start
+func (r *StartupReport) WriteTo(w io.Writer) (int64, error) {
+	events := r.Events()
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	var order []uint64
+	byStartup := make(map[uint64][]PhaseEvent)
+	var first, last time.Time
+	for _, event := range events {
+		if _, ok := byStartup[event.StartupID]; !ok {
+			order = append(order, event.StartupID)
+		}
+		byStartup[event.StartupID] = append(byStartup[event.StartupID], event)
+		end := event.Phase.Start.Add(event.Phase.Duration)
+		if first.IsZero() || event.Phase.Start.Before(first) {
+			first = event.Phase.Start
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+
+	cw := &countWriter{w: w}
+	fmt.Fprintf(cw, "startup report, wall time %s\n", last.Sub(first).Round(time.Millisecond))
+	tw := tabwriter.NewWriter(cw, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MODULE\tPHASE\tDURATION\t")
+	for _, id := range order {
+		var total time.Duration
+		var failed error
+		for _, event := range byStartup[id] {
+			status := ""
+			if event.Err != nil {
+				// StartError messages continue with the state and logs
+				status, _, _ = strings.Cut("failed: "+event.Err.Error(), "\n")
+				failed = event.Err
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", event.Module, event.Phase.Name, event.Phase.Duration.Round(time.Millisecond), status)
+			total += event.Phase.Duration
+		}
+		status := ""
+		if failed != nil {
+			status = "failed"
+		}
+		fmt.Fprintf(tw, "%s\ttotal\t%s\t%s\n", byStartup[id][0].Module, total.Round(time.Millisecond), status)
+	}
+	if err := tw.Flush(); err != nil {
+		return cw.n, err
+	}
+	return cw.n, cw.err
+}
+
+type countWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// Tracer records finished spans. It is a small interface so that any tracing library
+// can back it, the oteltrace package records nested OpenTelemetry spans instead.
+type Tracer interface {
+	RecordSpan(name string, start, end time.Time, attributes map[string]string, err error)
+}
+
+// TraceHook returns a StartupHook recording every phase as a span named "<module> <phase>"
+// with the module, phase and startup id as attributes
+func TraceHook(tracer Tracer) StartupHook {
+	return func(event PhaseEvent) {
+		if event.Phase.Name == "" {
+			return
+		}
+		tracer.RecordSpan(event.Module+" "+event.Phase.Name,
+			event.Phase.Start, event.Phase.Start.Add(event.Phase.Duration),
+			map[string]string{
+				"testcontainers.module":     event.Module,
+				"testcontainers.phase":      event.Phase.Name,
+				"testcontainers.startup_id": strconv.FormatUint(event.StartupID, 10),
+			}, event.Err)
+	}
+}
@@ -0,0 +1,109 @@
+package testcontainers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeTracer struct {
+	mu    sync.Mutex
+	spans []string
+}
+
+func (t *fakeTracer) RecordSpan(name string, start, end time.Time, attributes map[string]string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	span := name + " module=" + attributes["testcontainers.module"]
+	if end.Before(start) {
+		span += " ends before it starts"
+	}
+	if err != nil {
+		span += " error=" + err.Error()
+	}
+	t.spans = append(t.spans, span)
+}
+
+func TestStartupHooks(t *testing.T) {
+	var report StartupReport
+	tracer := &fakeTracer{}
+	remove := AddStartupHook(TraceHook(tracer))
+	defer remove()
+
+	kafka := NewStartup("kafka")
+	kafka.hooks = startupHooks(WithStartupHook(context.Background(), report.Hook))
+	kafka.Phase(PhasePull)
+	kafka.Phase(PhaseWait)
+	kafka.Phase("kafka version")
+	kafka.Done()
+
+	mongo := NewStartup("mongo")
+	mongo.hooks = startupHooks(WithStartupHook(context.Background(), report.Hook))
+	mongo.Phase(PhasePull)
+	mongo.Phase("replica set init")
+	_ = mongo.Fail(nil, errors.New("replica set init timed out\nlogs"))
+
+	events := report.Events()
+	require.Len(t, events, 5)
+	require.NotEqual(t, events[0].StartupID, events[4].StartupID)
+	require.Equal(t, "replica set init", events[4].Phase.Name)
+	require.Error(t, events[4].Err)
+	require.Equal(t, []string{
+		"kafka pull module=kafka",
+		"kafka wait module=kafka",
+		"kafka kafka version module=kafka",
+		"mongo pull module=mongo",
+		"mongo replica set init module=mongo error=replica set init timed out\nlogs",
+	}, tracer.spans)
+
+	remove()
+	NewStartup("redis").Phase(PhasePull)
+	NewStartup("redis").Done()
+	require.Len(t, tracer.spans, 5)
+
+	var out strings.Builder
+	_, err := report.WriteTo(&out)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.True(t, strings.HasPrefix(lines[0], "startup report, wall time "))
+	require.Len(t, lines, 9)
+	require.Regexp(t, `^kafka\s+total\s+\S+\s*$`, lines[5])
+	require.Regexp(t, `^mongo\s+replica set init\s+\S+\s+failed: replica set init timed out$`, lines[7])
+	require.Regexp(t, `^mongo\s+total\s+\S+\s+failed$`, lines[8])
+}
+
+func TestStartupLastEvent(t *testing.T) {
+	var events []PhaseEvent
+	hook := func(event PhaseEvent) { events = append(events, event) }
+	var report StartupReport
+
+	redis := NewStartup("redis")
+	redis.hooks = []StartupHook{hook, report.Hook}
+	redis.Phase(PhasePull)
+	redis.Phase("")
+	redis.Done()
+	redis.Done()
+	require.Len(t, events, 2)
+	require.False(t, events[0].Last)
+	// the start ends after its last phase
+	require.True(t, events[1].Last)
+	require.Empty(t, events[1].Phase.Name)
+	require.False(t, events[1].Phase.Start.Before(events[0].Phase.Start.Add(events[0].Phase.Duration)))
+	require.Len(t, report.Events(), 1)
+
+	events = nil
+	mongo := NewStartup("mongo")
+	mongo.hooks = []StartupHook{hook}
+	mongo.Phase(PhaseWait)
+	err := mongo.Fail(nil, errors.New("timeout"))
+	require.Error(t, err)
+	require.Len(t, events, 1)
+	require.True(t, events[0].Last)
+	require.Equal(t, PhaseWait, events[0].Phase.Name)
+	require.EqualError(t, events[0].Err, "timeout")
+}
@@ -3,19 +3,27 @@ package zookeeper
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/docker/go-connections/nat"
 	tc "github.com/mmadfox/testcontainers"
 	"github.com/testcontainers/testcontainers-go"
+	tcexec "github.com/testcontainers/testcontainers-go/exec"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
+// DataPath is the data directory of zookeeper, put on tmpfs by InMemoryStorage
+const DataPath = "/bitnami/zookeeper"
+
 // Options ...
 type Options struct {
 	tc.ContainerOptions
 	LogLevel string
 	ImageTag string
+	// ReadinessProbe replaces RuokProbe as the check that zookeeper is ready
+	ReadinessProbe tc.Probe
 }
 
 // Container ...
@@ -27,10 +35,14 @@ type Container struct {
 }
 
 // Terminate ...
-func (c *Container) Terminate(ctx context.Context) {
-	if c.Container != nil {
-		c.Container.Terminate(ctx)
+func (c *Container) Terminate(ctx context.Context) error {
+	if c.Container == nil {
+		return nil
+	}
+	if err := c.Container.Terminate(ctx); err != nil {
+		return fmt.Errorf("failed to terminate zookeeper container %s: %w", c.Container.GetContainerID(), err)
 	}
+	return nil
 }
 
 // ConnectionURI ...
@@ -41,6 +53,13 @@ func (c *Container) ConnectionURI() string {
 // Start ...
 func Start(ctx context.Context, options Options) (Container, error) {
 	var container Container
+	defaults, err := tc.ApplyDefaults("zookeeper", &options.ContainerOptions)
+	if err != nil {
+		return container, err
+	}
+	if options.LogLevel == "" {
+		options.LogLevel = defaults.LogLevel
+	}
 	port, err := nat.NewPort("", "2181")
 	if err != nil {
 		return container, fmt.Errorf("failed to build port: %v", err)
@@ -51,9 +70,9 @@ func Start(ctx context.Context, options Options) (Container, error) {
 		timeout = 5 * time.Minute // Default timeout
 	}
 
-	tag := "latest"
-	if options.ImageTag != "" {
-		tag = options.ImageTag
+	image, err := tc.Image("zookeeper", options.ImageTag)
+	if err != nil {
+		return container, err
 	}
 
 	logLevel := "WARN"
@@ -63,35 +82,80 @@ func Start(ctx context.Context, options Options) (Container, error) {
 
 	// Do not expose any ports per default
 	req := testcontainers.ContainerRequest{
-		Image: fmt.Sprintf("bitnami/zookeeper:%s", tag),
+		Image: image,
 		Env: map[string]string{
-			"ALLOW_ANONYMOUS_LOGIN": "yes",
-			"ZOO_LOG_LEVEL":         logLevel,
+			"ALLOW_ANONYMOUS_LOGIN":      "yes",
+			"ZOO_LOG_LEVEL":              logLevel,
+			"ZOO_4LW_COMMANDS_WHITELIST": "srvr, mntr, ruok",
 		},
-		WaitingFor: wait.ForListeningPort(port).WithStartupTimeout(timeout),
 	}
+	probe := options.ReadinessProbe
+	if probe == nil {
+		probe = RuokProbe()
+	}
+	req.WaitingFor = tc.ForProbe(probe).WithStartupTimeout(timeout)
 
-	tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest)
+	if err := tc.MergeRequest(&req, &options.ContainerOptions.ContainerRequest, options.MergePolicy); err != nil {
+		return container, err
+	}
+	tc.WithTestNaming(&req, options.ContainerOptions, "zookeeper")
+	if err := tc.WithResources(&req, options.ContainerOptions, DataPath); err != nil {
+		return container, err
+	}
 
-	zookeeperContainer, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
+	startup := tc.NewStartup("zookeeper")
+	zookeeperContainer, err := startup.StartContainer(ctx, req)
 	if err != nil {
-		return container, fmt.Errorf("failed to start container: %v", err)
+		return container, err
 	}
+	startup.Phase(tc.PhaseInspect)
 	container.Container = zookeeperContainer
 
 	realPort := port
 	if len(req.ExposedPorts) > 0 {
 		realPort, err = zookeeperContainer.MappedPort(ctx, port)
 		if err != nil {
-			return container, fmt.Errorf("failed to get exposed container port: %v", err)
+			return container, startup.Fail(zookeeperContainer, fmt.Errorf("failed to get exposed container port: %v", err))
 		}
 
 	}
 	container.Port = uint(realPort.Int())
 	container.Host = "zookeeper"
 
+	container.ContainerConfig, err = tc.InspectContainer(ctx, zookeeperContainer)
+	if err != nil {
+		return container, startup.Fail(zookeeperContainer, err)
+	}
+	startup.Done()
+
 	return container, nil
 }
+
+// ruokCmd sends ruok from inside the container, the client port is not exposed by default
+var ruokCmd = []string{"bash", "-c", "exec 3<>/dev/tcp/127.0.0.1/2181 && echo ruok >&3 && cat <&3"}
+
+// RuokProbe is ready when zookeeper answers imok to the ruok four letter word
+func RuokProbe() tc.Probe {
+	return func(ctx context.Context, target wait.StrategyTarget) error {
+		code, r, err := target.Exec(ctx, ruokCmd, tcexec.Multiplexed())
+		if err != nil {
+			return err
+		}
+		out, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if code != 0 || !strings.Contains(string(out), "imok") {
+			return fmt.Errorf("zookeeper is not ready: ruok answered %q (exit code %d)", out, code)
+		}
+		return nil
+	}
+}
+
+// NewModule returns a tc.Module starting a container with options
+func NewModule(options Options) *tc.ModuleOf[*Container] {
+	return tc.NewModule(func(ctx context.Context) (*Container, error) {
+		container, err := Start(ctx, options)
+		return &container, err
+	})
+}